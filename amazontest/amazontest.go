@@ -0,0 +1,148 @@
+// Package amazontest provides a stand-in Amazon server that serves
+// saved pages, allowing the scraping logic to be tested offline.
+//
+// The saved pages are kept once on the testdata directory of this
+// package and shared by the tests of all packages through Fixture.
+package amazontest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// Server is a fake Amazon server serving saved pages on
+// registered paths. Any unregistered path gets a 404.
+type Server struct {
+	*httptest.Server
+
+	t        testing.TB
+	mu       sync.Mutex
+	pages    map[string]Page
+	requests map[string]int
+}
+
+// Page is a response served by the fake server.
+type Page struct {
+	// File is the path of the file with the response body.
+	File string
+	// Status is the response status code, defaults to 200.
+	Status int
+}
+
+// NewServer creates and starts a new fake server.
+// The server is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		t:        t,
+		pages:    map[string]Page{},
+		requests: map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Handle registers the page to be served on the given path.
+// The path may have a query string, like "/s?page=2", matching
+// only requests that have the same query parameters (they may have
+// others). The page with most matching query parameters is served.
+func (s *Server) Handle(path string, page Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pages[path] = page
+}
+
+// Requests returns how many requests were received on the given path.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[path]
+}
+
+// Fixture returns the path of the saved page with the given name,
+// like "product_deal.html".
+func Fixture(name string) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata", name)
+}
+
+// Domain returns the domain of the server (host and port), it can
+// be used where an Amazon domain like www.amazon.com is expected.
+func (s *Server) Domain() string {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		s.t.Fatalf("amazontest: parsing server URL %q : %v", s.URL, err)
+	}
+	return u.Host
+}
+
+func (s *Server) serve(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	s.requests[req.URL.Path]++
+	page, ok := s.match(req.URL)
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	body, err := ioutil.ReadFile(page.File)
+	if err != nil {
+		s.t.Errorf("amazontest: reading page %q : %v", page.File, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status := page.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func (s *Server) match(u *url.URL) (Page, bool) {
+	var (
+		found   Page
+		ok      bool
+		matches = -1
+	)
+
+	query := u.Query()
+
+	for path, page := range s.pages {
+		pageURL, err := url.Parse(path)
+		if err != nil || pageURL.Path != u.Path {
+			continue
+		}
+
+		pageQuery := pageURL.Query()
+		if !contains(query, pageQuery) || len(pageQuery) <= matches {
+			continue
+		}
+
+		found, ok, matches = page, true, len(pageQuery)
+	}
+
+	return found, ok
+}
+
+func contains(query, params url.Values) bool {
+	for name := range params {
+		if query.Get(name) != params.Get(name) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,217 @@
+// Package availability classifies the availability texts of products,
+// like "Only 3 left in stock - order soon." or "Niet op voorraad.",
+// on the languages of the Amazon marketplaces.
+package availability
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/katcipis/amazoner/marketplace"
+)
+
+// Status is the status of the availability of a product.
+type Status string
+
+const (
+	Unknown Status = ""
+	InStock Status = "in-stock"
+	// LowStock is in stock, but with only a few units left.
+	LowStock   Status = "low-stock"
+	OutOfStock Status = "out-of-stock"
+	// OtherSellers is only available from other sellers, not on the buy box.
+	OtherSellers Status = "other-sellers"
+	PreOrder     Status = "pre-order"
+	// ShipsLater is available, but ships only after some days.
+	ShipsLater Status = "ships-later"
+)
+
+// Availability is the classified availability of a product.
+type Availability struct {
+	Status Status `json:"status"`
+	// Count is how many units are left, for LowStock.
+	Count int `json:"count,omitempty"`
+	// Days is the max days until the product ships, for ShipsLater.
+	Days int `json:"days,omitempty"`
+	// Text is the classified text.
+	Text string `json:"text"`
+}
+
+// language has the phrases of the availability texts of a language,
+// all lower case. Phrases are matched on the order of the fields,
+// so out of stock phrases come before the in stock ones.
+type language struct {
+	otherSellers []string
+	preOrder     []string
+	outOfStock   []string
+	// lowStock captures the units left.
+	lowStock *regexp.Regexp
+	// shipsIn captures the min and max (optional) amount
+	// of time until shipping and its unit.
+	shipsIn *regexp.Regexp
+	// units maps the time units of shipsIn to days.
+	units   map[string]int
+	inStock []string
+}
+
+// languages are the supported languages, on the order they are
+// tried when the language of the text is unknown.
+var languages = []struct {
+	code string
+	language
+}{
+	{"en", language{
+		otherSellers: []string{"available from these sellers", "see all buying options"},
+		preOrder:     []string{"pre-order", "preorder", "will be released on"},
+		outOfStock:   []string{"currently unavailable", "out of stock", "unavailable"},
+		lowStock:     regexp.MustCompile(`only (\d+) left in stock`),
+		shipsIn:      regexp.MustCompile(`(?:ships|dispatched) within (\d+)(?:\s*(?:to|-)\s*(\d+))?\s*(day|week|month)`),
+		units:        map[string]int{"day": 1, "week": 7, "month": 30},
+		inStock:      []string{"in stock"},
+	}},
+	{"nl", language{
+		otherSellers: []string{"beschikbaar bij deze verkopers", "alle koopopties bekijken"},
+		preOrder:     []string{"pre-order", "reserveer nu", "verschijnt op"},
+		outOfStock:   []string{"niet op voorraad", "momenteel niet verkrijgbaar", "momenteel niet beschikbaar"},
+		lowStock:     regexp.MustCompile(`(?:nog maar|slechts|nog) (\d+) (?:stuks? )?(?:op voorraad|beschikbaar)`),
+		shipsIn:      regexp.MustCompile(`binnen (\d+)(?:\s*(?:tot|-)\s*(\d+))?\s*(dag|week|weken|maand)`),
+		units:        map[string]int{"dag": 1, "week": 7, "weken": 7, "maand": 30},
+		inStock:      []string{"op voorraad"},
+	}},
+	{"de", language{
+		otherSellers: []string{"erhältlich bei diesen anbietern", "alle kaufoptionen anzeigen"},
+		preOrder:     []string{"vorbestellbar", "vorbestellen", "erscheint am"},
+		outOfStock:   []string{"derzeit nicht verfügbar", "nicht verfügbar", "nicht auf lager", "nicht vorrätig"},
+		lowStock:     regexp.MustCompile(`nur noch (\d+) (?:stück )?(?:auf lager|vorrätig)`),
+		shipsIn:      regexp.MustCompile(`versandfertig in (\d+)(?:\s*(?:bis|-)\s*(\d+))?\s*(tag|woche|monat)`),
+		units:        map[string]int{"tag": 1, "woche": 7, "monat": 30},
+		inStock:      []string{"auf lager", "vorrätig"},
+	}},
+	{"fr", language{
+		otherSellers: []string{"disponible auprès de ces vendeurs", "voir toutes les options d'achat"},
+		preOrder:     []string{"précommande", "pré-commande", "paraîtra le"},
+		outOfStock:   []string{"actuellement indisponible", "rupture de stock", "indisponible"},
+		lowStock:     regexp.MustCompile(`il ne reste plus que (\d+) exemplaire`),
+		shipsIn:      regexp.MustCompile(`expédié sous (\d+)(?:\s*(?:à|-)\s*(\d+))?\s*(jour|semaine|mois)`),
+		units:        map[string]int{"jour": 1, "semaine": 7, "mois": 30},
+		inStock:      []string{"en stock"},
+	}},
+	{"es", language{
+		otherSellers: []string{"disponible a través de estos vendedores", "ver todas las opciones de compra"},
+		preOrder:     []string{"preventa", "pre-venta", "saldrá a la venta el"},
+		outOfStock:   []string{"no disponible por el momento", "no disponible", "agotado", "sin stock"},
+		lowStock:     regexp.MustCompile(`solo queda(?:n|\(n\))? (\d+) en stock`),
+		shipsIn:      regexp.MustCompile(`se envía en (\d+)(?:\s*(?:a|-)\s*(\d+))?\s*(día|dia|semana|mes)`),
+		units:        map[string]int{"día": 1, "dia": 1, "semana": 7, "mes": 30},
+		inStock:      []string{"en stock"},
+	}},
+	{"it", language{
+		otherSellers: []string{"disponibile presso questi venditori", "visualizza tutte le opzioni di acquisto"},
+		preOrder:     []string{"preordina", "pre-ordine", "preordine"},
+		outOfStock:   []string{"attualmente non disponibile", "non disponibile", "esaurito"},
+		lowStock:     regexp.MustCompile(`solo (\d+)(?:\.|$| pezz[io]| rimast[io])`),
+		shipsIn:      regexp.MustCompile(`(?:spedit[oa]|spedizione) entro (\d+)(?:\s*(?:a|-)\s*(\d+))?\s*(giorn|settiman|mes)`),
+		units:        map[string]int{"giorn": 1, "settiman": 7, "mes": 30},
+		inStock:      []string{"disponibilità immediata", "disponibile"},
+	}},
+	{"pt", language{
+		otherSellers: []string{"disponível com estes vendedores", "disponível nestes vendedores", "ver todas as opções de compra"},
+		preOrder:     []string{"pré-venda", "pré-encomenda"},
+		outOfStock:   []string{"não disponível", "indisponível", "fora de estoque", "esgotado"},
+		lowStock:     regexp.MustCompile(`(?:apenas|somente|só restam|restam apenas) (\d+) (?:unidades? )?(?:em estoque|restantes?)`),
+		shipsIn:      regexp.MustCompile(`(?:enviado|envio) em (\d+)(?:\s*(?:a|-)\s*(\d+))?\s*(dia|semana|mês|mes)`),
+		units:        map[string]int{"dia": 1, "semana": 7, "mês": 30, "mes": 30},
+		inStock:      []string{"em estoque"},
+	}},
+}
+
+// Classify classifies the availability text on the given domain, using
+// the language of its marketplace. If the domain is unknown or the text
+// is not recognized on its language all languages are tried.
+func Classify(domain, text string) Availability {
+	m, _ := marketplace.Lookup(domain)
+	return ClassifyLanguage(m.Language, text)
+}
+
+// ClassifyLanguage classifies the availability text on the given
+// language, an ISO 639-1 code like "en" or "nl". If the language is
+// unknown or the text is not recognized on it all languages are tried.
+func ClassifyLanguage(lang, text string) Availability {
+	text = strings.Join(strings.Fields(text), " ")
+	lower := strings.ToLower(text)
+
+	for _, l := range languages {
+		if l.code != lang {
+			continue
+		}
+		if a := l.classify(lower); a.Status != Unknown {
+			a.Text = text
+			return a
+		}
+	}
+
+	for _, l := range languages {
+		if l.code == lang {
+			continue
+		}
+		if a := l.classify(lower); a.Status != Unknown {
+			a.Text = text
+			return a
+		}
+	}
+
+	return Availability{Status: Unknown, Text: text}
+}
+
+// InStock returns true if the product can be bought now, even if only
+// from other sellers. Products of Unknown availability are considered
+// in stock, since most products without availability are.
+func (a Availability) InStock() bool {
+	return a.Status != OutOfStock && a.Status != PreOrder
+}
+
+func (l language) classify(text string) Availability {
+	switch {
+	case containsAny(text, l.otherSellers):
+		return Availability{Status: OtherSellers}
+	case containsAny(text, l.preOrder):
+		return Availability{Status: PreOrder}
+	case containsAny(text, l.outOfStock):
+		return Availability{Status: OutOfStock}
+	}
+
+	if m := l.lowStock.FindStringSubmatch(text); m != nil {
+		count, _ := strconv.Atoi(m[1])
+		return Availability{Status: LowStock, Count: count}
+	}
+
+	if m := l.shipsIn.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if max, err := strconv.Atoi(m[2]); err == nil && max > n {
+			n = max
+		}
+		return Availability{Status: ShipsLater, Days: n * l.unitDays(m[3])}
+	}
+
+	if containsAny(text, l.inStock) {
+		return Availability{Status: InStock}
+	}
+	return Availability{Status: Unknown}
+}
+
+func (l language) unitDays(unit string) int {
+	if days, ok := l.units[unit]; ok {
+		return days
+	}
+	return 1
+}
+
+func containsAny(s string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(s, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package availability_test
+
+import (
+	"testing"
+
+	"github.com/katcipis/amazoner/availability"
+)
+
+func TestClassify(t *testing.T) {
+	type Test struct {
+		domain string
+		text   string
+		want   availability.Availability
+	}
+
+	tests := []Test{
+		// en
+		{domain: "www.amazon.com", text: " In Stock. ", want: availability.Availability{Status: availability.InStock}},
+		{domain: "www.amazon.com", text: "Only 3 left in stock - order soon.", want: availability.Availability{Status: availability.LowStock, Count: 3}},
+		{domain: "www.amazon.com", text: "Currently unavailable. We don't know when or if this item will be back in stock.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "www.amazon.com", text: "Temporarily out of stock.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "www.amazon.com", text: "Available from these sellers.", want: availability.Availability{Status: availability.OtherSellers}},
+		{domain: "www.amazon.com", text: "This item will be released on January 15, 2021. Pre-order now.", want: availability.Availability{Status: availability.PreOrder}},
+		{domain: "www.amazon.com", text: "Usually ships within 6 to 10 days.", want: availability.Availability{Status: availability.ShipsLater, Days: 10}},
+		{domain: "www.amazon.co.uk", text: "Usually dispatched within 1 to 2 months.", want: availability.Availability{Status: availability.ShipsLater, Days: 60}},
+		// nl
+		{domain: "www.amazon.nl", text: "Op voorraad.", want: availability.Availability{Status: availability.InStock}},
+		{domain: "www.amazon.nl", text: "Nog maar 2 op voorraad.", want: availability.Availability{Status: availability.LowStock, Count: 2}},
+		{domain: "www.amazon.nl", text: "Tijdelijk niet op voorraad.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "www.amazon.nl", text: "Beschikbaar bij deze verkopers.", want: availability.Availability{Status: availability.OtherSellers}},
+		{domain: "www.amazon.nl", text: "Wordt doorgaans binnen 1 tot 2 weken verzonden.", want: availability.Availability{Status: availability.ShipsLater, Days: 14}},
+		// de
+		{domain: "www.amazon.de", text: "Auf Lager.", want: availability.Availability{Status: availability.InStock}},
+		{domain: "www.amazon.de", text: "Nur noch 5 auf Lager (mehr ist unterwegs).", want: availability.Availability{Status: availability.LowStock, Count: 5}},
+		{domain: "www.amazon.de", text: "Derzeit nicht verfügbar.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "www.amazon.de", text: "Erhältlich bei diesen Anbietern.", want: availability.Availability{Status: availability.OtherSellers}},
+		{domain: "www.amazon.de", text: "Dieser Artikel erscheint am 3. März 2021. Jetzt vorbestellen.", want: availability.Availability{Status: availability.PreOrder}},
+		{domain: "www.amazon.de", text: "Gewöhnlich versandfertig in 3 bis 4 Tagen.", want: availability.Availability{Status: availability.ShipsLater, Days: 4}},
+		// fr
+		{domain: "www.amazon.fr", text: "En stock.", want: availability.Availability{Status: availability.InStock}},
+		{domain: "www.amazon.fr", text: "Il ne reste plus que 4 exemplaire(s) en stock.", want: availability.Availability{Status: availability.LowStock, Count: 4}},
+		{domain: "www.amazon.fr", text: "Actuellement indisponible.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "www.amazon.fr", text: "Disponible auprès de ces vendeurs.", want: availability.Availability{Status: availability.OtherSellers}},
+		{domain: "www.amazon.fr", text: "Habituellement expédié sous 2 à 3 semaines.", want: availability.Availability{Status: availability.ShipsLater, Days: 21}},
+		// es
+		{domain: "www.amazon.es", text: "En stock.", want: availability.Availability{Status: availability.InStock}},
+		{domain: "www.amazon.es", text: "Solo queda(n) 1 en stock.", want: availability.Availability{Status: availability.LowStock, Count: 1}},
+		{domain: "www.amazon.es", text: "Solo quedan 1 en stock.", want: availability.Availability{Status: availability.LowStock, Count: 1}},
+		{domain: "www.amazon.es", text: "No disponible por el momento.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "www.amazon.es", text: "Disponible a través de estos vendedores.", want: availability.Availability{Status: availability.OtherSellers}},
+		{domain: "www.amazon.es", text: "Normalmente se envía en 1 a 2 meses.", want: availability.Availability{Status: availability.ShipsLater, Days: 60}},
+		// it
+		{domain: "www.amazon.it", text: "Disponibilità immediata.", want: availability.Availability{Status: availability.InStock}},
+		{domain: "www.amazon.it", text: "Disponibilità: solo 2.", want: availability.Availability{Status: availability.LowStock, Count: 2}},
+		{domain: "www.amazon.it", text: "Solo 3 pezzi rimasti", want: availability.Availability{Status: availability.LowStock, Count: 3}},
+		{domain: "www.amazon.it", text: "Disponibile in solo 2 giorni.", want: availability.Availability{Status: availability.InStock}},
+		{domain: "www.amazon.it", text: "Attualmente non disponibile.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "www.amazon.it", text: "Disponibile presso questi venditori.", want: availability.Availability{Status: availability.OtherSellers}},
+		{domain: "www.amazon.it", text: "Generalmente spedito entro 4-5 giorni.", want: availability.Availability{Status: availability.ShipsLater, Days: 5}},
+		// pt
+		{domain: "www.amazon.com.br", text: "Em estoque.", want: availability.Availability{Status: availability.InStock}},
+		{domain: "www.amazon.com.br", text: "Apenas 3 em estoque.", want: availability.Availability{Status: availability.LowStock, Count: 3}},
+		{domain: "www.amazon.com.br", text: "Não disponível.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "www.amazon.com.br", text: "Disponível com estes vendedores.", want: availability.Availability{Status: availability.OtherSellers}},
+		{domain: "www.amazon.com.br", text: "Pré-venda. Este item será lançado em 10 de março.", want: availability.Availability{Status: availability.PreOrder}},
+		{domain: "www.amazon.com.br", text: "Normalmente enviado em 2 a 3 dias.", want: availability.Availability{Status: availability.ShipsLater, Days: 3}},
+		// Unknown domains try all languages
+		{domain: "127.0.0.1:8080", text: "Niet op voorraad.", want: availability.Availability{Status: availability.OutOfStock}},
+		{domain: "127.0.0.1:8080", text: "Nur noch 1 vorrätig.", want: availability.Availability{Status: availability.LowStock, Count: 1}},
+		// Text in another language than the marketplace one
+		{domain: "www.amazon.nl", text: "Only 7 left in stock.", want: availability.Availability{Status: availability.LowStock, Count: 7}},
+		{domain: "www.amazon.com", text: "", want: availability.Availability{Status: availability.Unknown}},
+	}
+
+	for _, test := range tests {
+		got := availability.Classify(test.domain, test.text)
+		test.want.Text = got.Text
+		if got != test.want {
+			t.Errorf("%s %q: got %+v; want %+v", test.domain, test.text, got, test.want)
+		}
+	}
+}
+
+func TestClassifyText(t *testing.T) {
+	got := availability.Classify("www.amazon.com", "\n   Only 3 left in stock -\n order soon.  ")
+	if want := "Only 3 left in stock - order soon."; got.Text != want {
+		t.Errorf("got text %q; want %q", got.Text, want)
+	}
+}
+
+func TestInStock(t *testing.T) {
+	type Test struct {
+		status availability.Status
+		want   bool
+	}
+
+	tests := []Test{
+		{status: availability.Unknown, want: true},
+		{status: availability.InStock, want: true},
+		{status: availability.LowStock, want: true},
+		{status: availability.OtherSellers, want: true},
+		{status: availability.ShipsLater, want: true},
+		{status: availability.OutOfStock, want: false},
+		{status: availability.PreOrder, want: false},
+	}
+
+	for _, test := range tests {
+		a := availability.Availability{Status: test.status}
+		if got := a.InStock(); got != test.want {
+			t.Errorf("status %q: got in stock %t; want %t", test.status, got, test.want)
+		}
+	}
+}
@@ -1,10 +1,9 @@
 package buy
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"net/url"
 	"os"
 	"strings"
@@ -12,64 +11,107 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/fedesog/webdriver"
+	"github.com/katcipis/amazoner/availability"
 	"github.com/katcipis/amazoner/chromedriver"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/marketplace"
+	"github.com/katcipis/amazoner/money"
 	"github.com/katcipis/amazoner/parser"
 	"github.com/katcipis/amazoner/product"
 )
 
 type Purchase struct {
-	Stock    string
-	Price    float64
-	Delivery string
+	// Stock is the availability text of the product.
+	Stock string
+	// Availability is the classified availability of the product.
+	Availability availability.Availability
+	Price        money.Money
+	// LandedPrice is the price plus shipping and import fees,
+	// the max price of the buy is enforced against it.
+	LandedPrice money.Money
+	Delivery    string
 }
 
+type Error string
+
+const (
+	ErrOutOfStock      Error = "out of stock"
+	ErrPriceAboveLimit Error = "price above limit"
+	ErrLoginFailed     Error = "login failed"
+)
+
 const throttleTime = time.Second
 
 // Do performs a buy with the given parameters.
-func Do(link string, maxPrice uint, email, password, userDataDir string, dryRun bool) (*Purchase, error) {
+// The client is used to get the product details, if nil a default client is used.
+func Do(c *fetch.Client, link string, maxPrice money.Money, email, password, userDataDir string, dryRun bool) (*Purchase, error) {
+	return DoContext(context.Background(), c, link, maxPrice, email, password, userDataDir, dryRun)
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
+// DoContext is like Do but with a context that can cancel the buy.
+// If the context is cancelled during the purchase the browser is closed
+// and no further step is taken.
+// The purchase fails with chromedriver.ErrProxyCredentials if its
+// proxy has credentials, since the browser can't authenticate on it.
+func DoContext(ctx context.Context, c *fetch.Client, link string, maxPrice money.Money, email, password, userDataDir string, dryRun bool) (*Purchase, error) {
+
+	// The purchase goes through a single proxy, if the client has any,
+	// so the browser session has the same IP of the product requests.
+	proxy := fetch.ProxyFromContext(ctx)
+	if proxy == nil && c != nil && c.Proxies != nil {
+		proxy = c.Proxies.Next()
+		ctx = fetch.WithProxy(ctx, proxy)
+	}
+	// Refused before any request, the browser can't authenticate on it
+	if proxy != nil && proxy.User != nil {
+		return nil, fmt.Errorf("%w : proxy %s://%s", chromedriver.ErrProxyCredentials, proxy.Scheme, proxy.Host)
+	}
 
 	// FIXME: We have some get/product parsing logic here that could be
 	// placed on the product package.
-	req, err := http.NewRequest(http.MethodGet, link, nil)
+	body, err := c.GetContext(ctx, link)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("buying request failed : %w", err)
 	}
 
-	res, err := client.Do(req)
+	doc, err := goquery.NewDocumentFromReader(body)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		// Lazy ignoring the error here
-		resBody, _ := ioutil.ReadAll(res.Body)
-		return nil, fmt.Errorf("buying request failed, unexpected status code %d, body:\n%s\n", res.StatusCode, resBody)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	linkURL, err := url.Parse(link)
 	if err != nil {
 		return nil, err
 	}
 
-	availability, ok := parser.ParseById(doc, "availability")
+	stock, ok := parser.ParseById(doc, "availability")
 	if !ok {
 		return nil, errors.New("could not parse availability due to empty string")
 	}
 
-	if !checkAvailability(availability) {
-		return nil, fmt.Errorf("no stock available: %s", availability)
+	avail := availability.Classify(linkURL.Host, stock)
+	if !avail.InStock() {
+		return nil, fmt.Errorf("%w (%s): %s", ErrOutOfStock, avail.Status, stock)
+	}
+
+	offer, err := product.ParseBuyingOfferContext(ctx, c, doc, link)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the price of product with availability '%s'\n%w", stock, err)
+	}
+
+	price := offer.Price
+	landedPrice, err := offer.LandedPrice()
+	if err != nil {
+		return nil, fmt.Errorf("could not calculate landed price for product with availability '%s': %v", stock, err)
 	}
 
-	price, err := product.ParsePrice(doc, link)
+	cmp, err := landedPrice.Cmp(maxPrice)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing the price of product with availability '%s'\n%v", availability, err)
+		return nil, fmt.Errorf("could not compare price with maximum for product with availability '%s': %v", stock, err)
 	}
 
-	if uint(price) > maxPrice {
-		return nil, fmt.Errorf("could not buy product with availability '%s', price '%v' is higher than maximum '%d'.", availability, price, maxPrice)
+	if cmp > 0 {
+		return nil, fmt.Errorf("%w : could not buy product with availability '%s', landed price '%v' (price '%v', shipping '%v', import fees '%v') is higher than maximum '%v'.", ErrPriceAboveLimit, stock, landedPrice, price, offer.Shipping, offer.ImportFees, maxPrice)
 	}
 
 	delivery, ok := parser.ParseById(doc, "deliveryMessageMirId")
@@ -77,75 +119,74 @@ func Do(link string, maxPrice uint, email, password, userDataDir string, dryRun
 		fmt.Fprintln(os.Stderr, "could not parse delivery due to empty string")
 	}
 
-	err = makePurchase(link, email, password, userDataDir, availability, dryRun)
+	err = makePurchase(ctx, link, maxPrice, email, password, userDataDir, proxy, avail.Status, dryRun)
 	if err != nil {
-		return nil, fmt.Errorf("error while making purchase of product with availability '%s', price '%v' and delivery '%s'. err: %v", availability, price, delivery, err)
+		return nil, fmt.Errorf("error while making purchase of product with availability '%s', price '%v' and delivery '%s'. err: %w", stock, price, delivery, err)
 	}
 
 	return &Purchase{
-		Stock:    availability,
-		Price:    price,
-		Delivery: delivery,
+		Stock:        stock,
+		Availability: avail,
+		Price:        price,
+		LandedPrice:  landedPrice,
+		Delivery:     delivery,
 	}, nil
 }
 
-func checkAvailability(availability string) bool {
-	outOfStockPhrases := []string{
-		"niet op voorraad",
-		"unavailable",
-	}
-	for _, phrase := range outOfStockPhrases {
-		if strings.Contains(availability, phrase) {
-			return false
-		}
-	}
-	return true
-}
-
-func makePurchase(link, email, password, userDataDir, availability string, dryRun bool) error {
+func makePurchase(ctx context.Context, link string, maxPrice money.Money, email, password, userDataDir string, proxy *url.URL, status availability.Status, dryRun bool) error {
 	// Start Chromedriver
-	browser, err := chromedriver.NewBrowser(link, userDataDir)
+	browser, err := chromedriver.NewBrowserWithProxy(link, userDataDir, proxy)
 	if err != nil {
 		return err
 	}
 	defer browser.Close()
 
-	time.Sleep(throttleTime)
+	if err := fetch.Sleep(ctx, throttleTime); err != nil {
+		return err
+	}
 
 	if userDataDir == "" {
-		err = Login(browser.Session, email, password)
+		err = LoginContext(ctx, browser.Session, email, password)
 		if err != nil {
 			return err
 		}
 
-		time.Sleep(throttleTime)
+		if err := fetch.Sleep(ctx, throttleTime); err != nil {
+			return err
+		}
 	}
 
-	switch availability {
-	case "Available from these sellers.":
-	case "Beschikbaar bij deze verkopers.":
+	switch status {
+	case availability.OtherSellers:
 		linkUrl, err := url.Parse(link)
 		if err != nil {
 			return err
 		}
 
-		entrypointURL := "https://" + linkUrl.Hostname()
+		entrypointURL := linkUrl.Scheme + "://" + linkUrl.Host
+		currency := ""
+		if m, ok := marketplace.Lookup(linkUrl.Host); ok {
+			currency = m.Currency
+		}
 
-		err = buyFromSellers(browser.Session, dryRun, entrypointURL)
+		err = buyFromSellers(ctx, browser.Session, maxPrice, currency, dryRun, entrypointURL)
 	default:
-		err = buyNow(browser.Session, dryRun)
+		err = buyNow(ctx, browser.Session, dryRun)
 	}
 
-	time.Sleep(throttleTime)
-
 	if err != nil {
 		return err
 	}
 
+	fetch.Sleep(ctx, throttleTime)
 	return nil
 }
 
-func buyFromSellers(session *webdriver.Session, dryRun bool, entrypointURL string) error {
+// buyFromSellers buys the best offer of the other sellers. The offer is
+// chosen again on the browser, so its landed price is checked against
+// the max price before it goes into the cart. The prices of the offers
+// are parsed on the local currency of the marketplace, if known.
+func buyFromSellers(ctx context.Context, session *webdriver.Session, maxPrice money.Money, currency string, dryRun bool, entrypointURL string) error {
 
 	buySellersBtn, err := session.FindElement(webdriver.ID, "buybox-see-all-buying-choices")
 	if err != nil {
@@ -156,13 +197,19 @@ func buyFromSellers(session *webdriver.Session, dryRun bool, entrypointURL strin
 		return err
 	}
 
-	time.Sleep(throttleTime)
+	if err := fetch.Sleep(ctx, throttleTime); err != nil {
+		return err
+	}
 
-	bestOffer, err := getBestOffer(session)
+	bestOffer, offer, err := getBestOffer(session, currency)
 	if err != nil {
 		return err
 	}
 
+	if err := checkPrice(offer, maxPrice); err != nil {
+		return err
+	}
+
 	addToCartBtn, err := bestOffer.FindElement(webdriver.Name, "submit.addToCart")
 	if err != nil {
 		return err
@@ -172,11 +219,17 @@ func buyFromSellers(session *webdriver.Session, dryRun bool, entrypointURL strin
 		return err
 	}
 
-	time.Sleep(throttleTime)
+	if err := fetch.Sleep(ctx, throttleTime); err != nil {
+		return err
+	}
 
-	session.Url(entrypointURL + "/gp/cart/view.html")
+	if err := session.Url(entrypointURL + "/gp/cart/view.html"); err != nil {
+		return err
+	}
 
-	time.Sleep(throttleTime)
+	if err := fetch.Sleep(ctx, throttleTime); err != nil {
+		return err
+	}
 
 	checkoutBtn, err := session.FindElement(webdriver.ID, "sc-buy-box-ptc-button")
 	if err != nil {
@@ -187,7 +240,9 @@ func buyFromSellers(session *webdriver.Session, dryRun bool, entrypointURL strin
 		return err
 	}
 
-	time.Sleep(throttleTime)
+	if err := fetch.Sleep(ctx, throttleTime); err != nil {
+		return err
+	}
 
 	placeOrderBtn, err := session.FindElement(webdriver.ID, "placeYourOrder")
 	if err != nil {
@@ -201,12 +256,13 @@ func buyFromSellers(session *webdriver.Session, dryRun bool, entrypointURL strin
 	if err = placeOrderBtn.Click(); err != nil {
 		return err
 	}
-	time.Sleep(throttleTime)
 
+	// The order is placed, so cancelling now is not an error
+	fetch.Sleep(ctx, throttleTime)
 	return nil
 }
 
-func buyNow(session *webdriver.Session, dryRun bool) error {
+func buyNow(ctx context.Context, session *webdriver.Session, dryRun bool) error {
 	buyNowBtn, err := session.FindElement(webdriver.ID, "buy-now-button")
 	if err != nil {
 		return err
@@ -216,7 +272,9 @@ func buyNow(session *webdriver.Session, dryRun bool) error {
 		return err
 	}
 
-	time.Sleep(5 * time.Second)
+	if err := fetch.Sleep(ctx, 5*time.Second); err != nil {
+		return err
+	}
 
 	if err = session.FocusOnFrame("turbo-checkout-iframe"); err != nil {
 		return err
@@ -233,27 +291,63 @@ func buyNow(session *webdriver.Session, dryRun bool) error {
 	if err = placeOrderBtn.Click(); err != nil {
 		return err
 	}
-	time.Sleep(throttleTime)
 
+	// The order is placed, so cancelling now is not an error
+	fetch.Sleep(ctx, throttleTime)
 	return nil
 }
 
-func getBestOffer(session *webdriver.Session) (webdriver.WebElement, error) {
-	offers, err := session.FindElements(webdriver.ID, "aod-offer")
+// getBestOffer returns the element of the best offer of the
+// sellers and the offer parsed from it, on the given local currency.
+func getBestOffer(session *webdriver.Session, currency string) (webdriver.WebElement, product.Offer, error) {
+	source, err := session.Source()
 	if err != nil {
-		return webdriver.WebElement{}, err
+		return webdriver.WebElement{}, product.Offer{}, err
 	}
 
-	if len(offers) == 0 {
-		offers, err = session.FindElements(webdriver.CSS_Selector, "#olpOfferList > div > div > div")
-		if err != nil {
-			return webdriver.WebElement{}, err
-		}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(source))
+	if err != nil {
+		return webdriver.WebElement{}, product.Offer{}, err
 	}
 
-	if len(offers) == 0 {
-		return webdriver.WebElement{}, errors.New("could not parse best offer from sellers")
+	parsed := product.ParseOffersIn(doc, currency)
+	best, ok := product.BestOffer(parsed)
+	if !ok {
+		return webdriver.WebElement{}, product.Offer{}, errors.New("could not parse best offer from sellers")
 	}
 
-	return offers[0], nil
+	// The elements are selected on the same order the offers are parsed
+	offers, err := session.FindElements(webdriver.CSS_Selector, product.OfferSelector)
+	if err != nil {
+		return webdriver.WebElement{}, product.Offer{}, err
+	}
+
+	if best >= len(offers) {
+		return webdriver.WebElement{}, product.Offer{}, fmt.Errorf("best offer %d not found, page has %d offers", best, len(offers))
+	}
+
+	return offers[best], parsed[best], nil
+}
+
+// checkPrice checks that the landed price of the offer, its price plus
+// shipping and import fees, is not above the max price.
+func checkPrice(offer product.Offer, maxPrice money.Money) error {
+	landedPrice, err := offer.LandedPrice()
+	if err != nil {
+		return fmt.Errorf("could not calculate landed price of offer from seller '%s': %v", offer.Seller, err)
+	}
+
+	cmp, err := landedPrice.Cmp(maxPrice)
+	if err != nil {
+		return fmt.Errorf("could not compare price of offer from seller '%s' with maximum: %v", offer.Seller, err)
+	}
+
+	if cmp > 0 {
+		return fmt.Errorf("%w : offer from seller '%s' has landed price '%v' (price '%v', shipping '%v', import fees '%v') higher than maximum '%v'", ErrPriceAboveLimit, offer.Seller, landedPrice, offer.Price, offer.Shipping, offer.ImportFees, maxPrice)
+	}
+	return nil
+}
+
+func (e Error) Error() string {
+	return string(e)
 }
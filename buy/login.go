@@ -1,12 +1,30 @@
 package buy
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/fedesog/webdriver"
+	"github.com/katcipis/amazoner/fetch"
 )
 
 func Login(session *webdriver.Session, email, password string) error {
+	return LoginContext(context.Background(), session, email, password)
+}
+
+// LoginContext is like Login but with a context that can cancel the login.
+// Failures other than the cancellation of the context are ErrLoginFailed.
+func LoginContext(ctx context.Context, session *webdriver.Session, email, password string) error {
+	err := login(ctx, session, email, password)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	return fmt.Errorf("%w : %v", ErrLoginFailed, err)
+}
+
+func login(ctx context.Context, session *webdriver.Session, email, password string) error {
 
 	accountList, err := session.FindElement(webdriver.ID, "nav-link-accountList")
 	if err != nil {
@@ -18,7 +36,9 @@ func Login(session *webdriver.Session, email, password string) error {
 		return err
 	}
 
-	time.Sleep(2 * time.Second)
+	if err := fetch.Sleep(ctx, 2*time.Second); err != nil {
+		return err
+	}
 
 	emailInput, err := session.FindElement(webdriver.ID, "ap_email")
 	if err != nil {
@@ -40,7 +60,9 @@ func Login(session *webdriver.Session, email, password string) error {
 		return err
 	}
 
-	time.Sleep(2 * time.Second)
+	if err := fetch.Sleep(ctx, 2*time.Second); err != nil {
+		return err
+	}
 
 	passwordInput, err := session.FindElement(webdriver.ID, "ap_password")
 	if err != nil {
@@ -62,5 +84,14 @@ func Login(session *webdriver.Session, email, password string) error {
 		return err
 	}
 
+	if err := fetch.Sleep(ctx, 2*time.Second); err != nil {
+		return err
+	}
+
+	// Wrong credentials keep the sign in page with an error box
+	if _, err := session.FindElement(webdriver.ID, "auth-error-message-box"); err == nil {
+		return errors.New("credentials rejected")
+	}
+
 	return nil
 }
@@ -0,0 +1,89 @@
+package chromedriver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/katcipis/amazoner/fetch"
+)
+
+// ManualSolver is a fetch.CaptchaSolver that opens the captcha on a
+// browser for someone to solve it, resuming with the browser cookies.
+type ManualSolver struct {
+	// UserDataDir is the chrome user data dir, optional.
+	UserDataDir string
+	// Out receives the instructions to solve the captcha,
+	// defaults to os.Stderr.
+	Out io.Writer
+	// Timeout is how long to wait for the captcha to be
+	// solved, defaults to DefaultSolveTimeout.
+	Timeout time.Duration
+}
+
+const DefaultSolveTimeout = 5 * time.Minute
+
+// rateLimitRefresh is the wait before reloading the page
+// when Amazon answers with its rate limit page.
+const rateLimitRefresh = 10 * time.Second
+
+// Solve opens the link on a browser and waits until it is no
+// longer a captcha challenge, returning the browser cookies.
+// The rate limit page, which Amazon may send after the captcha,
+// is reloaded until it goes away. The browser goes through the
+// proxy on the context, if any.
+func (s *ManualSolver) Solve(ctx context.Context, link string) ([]*http.Cookie, error) {
+	browser, err := NewBrowserWithProxy(link, s.UserDataDir, fetch.ProxyFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer browser.Close()
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultSolveTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	out := s.Out
+	if out == nil {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "captcha challenge on %s, please solve it on the browser\n", link)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	refreshed := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting captcha to be solved : %w", ctx.Err())
+		}
+
+		source, err := browser.Session.Source()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case fetch.IsCaptcha([]byte(source)):
+			// Still waiting someone to solve it
+		case fetch.IsRateLimitPage([]byte(source)):
+			if time.Since(refreshed) < rateLimitRefresh {
+				continue
+			}
+			if err := browser.Session.Refresh(); err != nil {
+				return nil, fmt.Errorf("reloading rate limit page : %w", err)
+			}
+			refreshed = time.Now()
+		default:
+			return browser.Cookies()
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package chromedriver
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
 	"github.com/fedesog/webdriver"
 )
 
@@ -10,6 +15,19 @@ type Browser struct {
 }
 
 func NewBrowser(entrypointURL, userDataDir string) (*Browser, error) {
+	return NewBrowserWithProxy(entrypointURL, userDataDir, nil)
+}
+
+// NewBrowserWithProxy is like NewBrowser but with the browser going
+// through the given proxy, if not nil. Chrome doesn't support the
+// credentials of the proxy URL, so it fails with ErrProxyCredentials
+// if the proxy has them, instead of going through the proxy without
+// authenticating or, worse, not going through it at all.
+func NewBrowserWithProxy(entrypointURL, userDataDir string, proxy *url.URL) (*Browser, error) {
+	if proxy != nil && proxy.User != nil {
+		return nil, fmt.Errorf("%w : proxy %s://%s", ErrProxyCredentials, proxy.Scheme, proxy.Host)
+	}
+
 	chromeDriver := webdriver.NewChromeDriver("chromedriver")
 	err := chromeDriver.Start()
 	if err != nil {
@@ -20,9 +38,16 @@ func NewBrowser(entrypointURL, userDataDir string) (*Browser, error) {
 		"Platform": "Linux",
 	}
 
+	args := []string{}
 	if userDataDir != "" {
+		args = append(args, "user-data-dir="+userDataDir)
+	}
+	if proxy != nil {
+		args = append(args, "proxy-server="+proxy.Scheme+"://"+proxy.Host)
+	}
+	if len(args) > 0 {
 		desired["goog:chromeOptions"] = map[string]interface{}{
-			"args": []string{"user-data-dir=" + userDataDir},
+			"args": args,
 		}
 	}
 	required := webdriver.Capabilities{}
@@ -39,7 +64,38 @@ func NewBrowser(entrypointURL, userDataDir string) (*Browser, error) {
 	return &Browser{chromeDriver, session}, nil
 }
 
+// Cookies returns the cookies of the current page of the browser.
+func (b *Browser) Cookies() ([]*http.Cookie, error) {
+	cookies, err := b.Session.GetCookies()
+	if err != nil {
+		return nil, err
+	}
+
+	httpCookies := make([]*http.Cookie, len(cookies))
+	for i, c := range cookies {
+		httpCookies[i] = &http.Cookie{
+			Name:   c.Name,
+			Value:  c.Value,
+			Path:   c.Path,
+			Domain: c.Domain,
+			Secure: c.Secure,
+		}
+		if c.Expiry > 0 {
+			httpCookies[i].Expires = time.Unix(int64(c.Expiry), 0)
+		}
+	}
+	return httpCookies, nil
+}
+
 func (b *Browser) Close() {
 	b.Session.Delete()
 	b.ChromeDriver.Stop()
 }
+
+type Error string
+
+const ErrProxyCredentials Error = "browser can't use proxies with credentials"
+
+func (e Error) Error() string {
+	return string(e)
+}
@@ -0,0 +1,40 @@
+// Package cli has the helpers shared by the commands, like
+// opening the cookies and proxies given on their flags.
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/katcipis/amazoner/fetch"
+)
+
+// OpenCookies opens the cookie jar on the given path,
+// if the path is empty cookies are kept only in memory.
+func OpenCookies(path string) (*fetch.CookieJar, error) {
+	if path == "" {
+		return fetch.NewCookieJar(), nil
+	}
+	return fetch.OpenCookieJar(path)
+}
+
+// SaveCookies saves the cookies of the jar, printing
+// failures on stderr, so it can be deferred.
+func SaveCookies(jar *fetch.CookieJar) {
+	if err := jar.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to save cookies : %v\n", err)
+	}
+}
+
+// OpenProxies opens the proxy pool with the proxies on the given
+// file, one per line. If the path is empty there is no pool.
+func OpenProxies(path string) (*fetch.ProxyPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+	urls, err := fetch.LoadProxies(path)
+	if err != nil {
+		return nil, err
+	}
+	return fetch.NewProxyPool(urls, fetch.RoundRobin, 0)
+}
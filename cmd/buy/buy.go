@@ -6,46 +6,82 @@ import (
 	"os"
 
 	"github.com/katcipis/amazoner/buy"
+	"github.com/katcipis/amazoner/chromedriver"
+	"github.com/katcipis/amazoner/cli"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/interrupt"
+	"github.com/katcipis/amazoner/money"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run buys the product, returning the exit code, so the cookies
+// are saved before exiting.
+func run() int {
 	var (
 		link        string
-		maxPrice    uint
+		maxPrice    = money.Money{Amount: 100000}
 		email       string
 		password    string
 		userDataDir string
 		dryRun      bool
+		captcha     bool
+		cookies     string
+		proxies     string
 	)
 
 	flag.StringVar(&link, "link", "", "link of product to buy")
-	flag.UintVar(&maxPrice, "max", 1000, "max price of product")
+	flag.Var(&maxPrice, "max", "max landed price of product, including shipping and import fees (eg. 1000, 999.99 or \"$1,234.56\")")
 	flag.StringVar(&email, "email", "", "your Amazon user email")
 	flag.StringVar(&password, "password", "", "your Amazon user password")
 	flag.StringVar(&userDataDir, "user-data-dir", "", "your chrome user data dir")
 	flag.BoolVar(&dryRun, "dryrun", false, "if true it just opens page without buying")
+	flag.StringVar(&cookies, "cookies", "", "path of a file to keep the cookies of Amazon across runs")
+	flag.StringVar(&proxies, "proxies", "", "path of a file with the proxies of the requests, one URL per line, like socks5://10.0.0.2:1080. The browser of the purchase can't use proxies with credentials")
+	flag.BoolVar(&captcha, "solve-captcha", false, "open captcha challenges on chrome to be solved manually")
 
 	flag.Parse()
 
 	if link == "" {
 		fmt.Println("link is an obligatory parameter")
-		os.Exit(1)
-		return
+		return 1
 	}
 
 	if userDataDir == "" {
 		if email == "" || password == "" {
 			fmt.Println("if you are not using user-data-dir, please provide email and password")
-			os.Exit(1)
-			return
+			return 1
 		}
 	}
 
-	fmt.Printf("buy product from link %q max price %d\n\n", link, maxPrice)
+	jar, err := cli.OpenCookies(cookies)
+	if err != nil {
+		fmt.Printf("unable to open cookies %q : %v\n", cookies, err)
+		return 1
+	}
+	defer cli.SaveCookies(jar)
+
+	pool, err := cli.OpenProxies(proxies)
+	if err != nil {
+		fmt.Printf("unable to open proxies %q : %v\n", proxies, err)
+		return 1
+	}
+
+	fmt.Printf("buy product from link %q max price %v\n\n", link, maxPrice)
 
 	fmt.Println("==== BUY START ====")
 
-	purchase, err := buy.Do(link, maxPrice, email, password, userDataDir, dryRun)
+	ctx, cancel := interrupt.Context()
+	defer cancel()
+
+	client := &fetch.Client{Jar: jar, Proxies: pool}
+	if captcha {
+		client.CaptchaSolver = &chromedriver.ManualSolver{UserDataDir: userDataDir}
+	}
+
+	purchase, err := buy.DoContext(ctx, client, link, maxPrice, email, password, userDataDir, dryRun)
 	fmt.Printf("%+v\n", purchase)
 	fmt.Println("==== BUY END ====")
 
@@ -54,6 +90,7 @@ func main() {
 		logerr(err.Error())
 		logerr("==== ERRORS END ====")
 	}
+	return 0
 }
 
 func logerr(msg string) {
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/katcipis/amazoner/buy"
+	"github.com/katcipis/amazoner/cli"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/interrupt"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/rules"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run evaluates the rules, returning the exit code. The ledger and
+// the cookies are closed and saved by deferred calls before exiting.
+func run() int {
+	var (
+		config      string
+		ledger      string
+		email       string
+		password    string
+		userDataDir string
+		rate        time.Duration
+		dryRun      bool
+		once        bool
+		cookies     string
+		retries     int
+		proxies     string
+	)
+
+	flag.StringVar(&config, "config", "", "path of the JSON rules config")
+	flag.StringVar(&ledger, "ledger", "purchases.jsonl", "path of the file recording the purchases")
+	flag.StringVar(&email, "email", "", "your Amazon user email")
+	flag.StringVar(&password, "password", "", "your Amazon user password")
+	flag.StringVar(&userDataDir, "user-data-dir", "", "your chrome user data dir")
+	flag.DurationVar(&rate, "rate", time.Second, "min interval between requests to Amazon")
+	flag.IntVar(&retries, "retries", fetch.DefaultRetry.Attempts, "max attempts of requests failing with transient errors, like status 503")
+	flag.BoolVar(&dryRun, "dryrun", false, "if true it only explains why each rule did or didn't fire, without buying")
+	flag.BoolVar(&once, "once", false, "evaluate the rules once and exit")
+	flag.StringVar(&cookies, "cookies", "", "path of a file to keep the cookies of Amazon across runs")
+	flag.StringVar(&proxies, "proxies", "", "path of a file with the proxies of the requests, one URL per line, like socks5://10.0.0.2:1080. The browser of the purchase can't use proxies with credentials")
+
+	flag.Parse()
+
+	if config == "" {
+		fmt.Println("config is an obligatory parameter")
+		return 1
+	}
+
+	if !dryRun && userDataDir == "" {
+		if email == "" || password == "" {
+			fmt.Println("if you are not using user-data-dir, please provide email and password")
+			return 1
+		}
+	}
+
+	cfg, err := rules.LoadConfig(config)
+	if err != nil {
+		fmt.Printf("unable to load config %q : %v\n", config, err)
+		return 1
+	}
+
+	engine, err := cfg.Engine()
+	if err != nil {
+		fmt.Printf("unable to load config %q : %v\n", config, err)
+		return 1
+	}
+
+	engine.Ledger, err = rules.OpenLedger(ledger)
+	if err != nil {
+		fmt.Printf("unable to open ledger %q : %v\n", ledger, err)
+		return 1
+	}
+	defer engine.Ledger.Close()
+
+	jar, err := cli.OpenCookies(cookies)
+	if err != nil {
+		fmt.Printf("unable to open cookies %q : %v\n", cookies, err)
+		return 1
+	}
+	defer cli.SaveCookies(jar)
+
+	retry := fetch.DefaultRetry
+	retry.Attempts = retries
+
+	pool, err := cli.OpenProxies(proxies)
+	if err != nil {
+		fmt.Printf("unable to open proxies %q : %v\n", proxies, err)
+		return 1
+	}
+
+	client := &fetch.Client{Limiter: fetch.NewLimiter(rate, 1), Jar: jar, Retry: &retry, Proxies: pool}
+	engine.Client = client
+	engine.DryRun = dryRun
+	engine.Buyer = rules.BuyerFunc(func(ctx context.Context, link string, maxPrice money.Money) (*buy.Purchase, error) {
+		return buy.DoContext(ctx, client, link, maxPrice, email, password, userDataDir, false)
+	})
+	engine.OnEvaluation = func(ev rules.Evaluation) {
+		fmt.Printf("%s %s", time.Now().Format("2006-01-02 15:04:05"), ev.Explain())
+	}
+	engine.OnError = func(err error) {
+		logerr(fmt.Sprintf("%s %v", time.Now().Format("2006-01-02 15:04:05"), err))
+	}
+
+	ctx, cancel := interrupt.Context()
+	defer cancel()
+
+	if once {
+		if _, err := engine.Check(ctx); err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("evaluating %d rules\n", len(engine.Rules))
+	if err := engine.Run(ctx); err != nil && err != context.Canceled {
+		logerr(err.Error())
+	}
+	return 0
+}
+
+func logerr(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+}
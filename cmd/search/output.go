@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+	"github.com/katcipis/amazoner/search"
+)
+
+// Output formats of the results.
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputCSV   = "csv"
+	outputTable = "table"
+)
+
+var outputs = []string{outputText, outputJSON, outputJSONL, outputCSV, outputTable}
+
+// maxNameWidth is the max width of product names on tables.
+const maxNameWidth = 60
+
+// record is a product found, as written on the output. When comparing
+// domains the prices are converted to the currency of the comparison.
+type record struct {
+	Domain       string
+	ASIN         string
+	Name         string
+	URL          string
+	Price        money.Money
+	LandedPrice  money.Money
+	Availability string
+	Rating       float64
+	Reviews      int
+	Prime        bool
+}
+
+// jsonRecord is the record on the JSON outputs, with the prices
+// as exact decimal numbers, like 1234.56, and their currency.
+type jsonRecord struct {
+	Domain       string      `json:"domain,omitempty"`
+	ASIN         string      `json:"asin"`
+	Name         string      `json:"name"`
+	URL          string      `json:"url"`
+	Price        json.Number `json:"price"`
+	LandedPrice  json.Number `json:"landed_price"`
+	Currency     string      `json:"currency,omitempty"`
+	Availability string      `json:"availability,omitempty"`
+	Rating       float64     `json:"rating,omitempty"`
+	Reviews      int         `json:"reviews,omitempty"`
+	Prime        bool        `json:"prime,omitempty"`
+}
+
+func (r record) json() jsonRecord {
+	currency := r.LandedPrice.Currency
+	if currency == "" {
+		currency = r.Price.Currency
+	}
+	return jsonRecord{
+		Domain:       r.Domain,
+		ASIN:         r.ASIN,
+		Name:         r.Name,
+		URL:          r.URL,
+		Price:        json.Number(decimal(r.Price)),
+		LandedPrice:  json.Number(decimal(r.LandedPrice)),
+		Currency:     currency,
+		Availability: r.Availability,
+		Rating:       r.Rating,
+		Reviews:      r.Reviews,
+		Prime:        r.Prime,
+	}
+}
+
+// errorRecord is an error of the search, as written on the JSON output.
+type errorRecord struct {
+	// URL is the URL of the product that failed, if any.
+	URL string `json:"url,omitempty"`
+	// Kind classifies the error, like "captcha" or "not-found".
+	Kind string `json:"kind"`
+	// Status is the HTTP status of the response, if any.
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error"`
+}
+
+func productRecord(prod product.Product) record {
+	landed, err := prod.LandedPrice()
+	if err != nil {
+		landed = prod.Price
+	}
+	return record{
+		ASIN:         prod.ASIN,
+		Name:         prod.Name,
+		URL:          prod.URL,
+		Price:        prod.Price,
+		LandedPrice:  landed,
+		Availability: string(prod.Stock().Status),
+		Rating:       prod.Rating,
+		Reviews:      prod.Reviews,
+		Prime:        prod.Prime,
+	}
+}
+
+func resultRecord(res search.Result) record {
+	r := productRecord(res.Product)
+	r.Domain = res.Domain
+	r.Price = res.Price
+	r.LandedPrice = res.LandedPrice
+	return r
+}
+
+// writeRecords writes the records on the given format, which can't be
+// text. Only the JSON format has the errors, on the other formats they
+// must be reported apart.
+func writeRecords(w io.Writer, format string, records []record, err error) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, records, err)
+	case outputJSONL:
+		return writeJSONL(w, records)
+	case outputCSV:
+		return writeCSV(w, records)
+	case outputTable:
+		return writeTable(w, records)
+	}
+	return fmt.Errorf("unknown output %q", format)
+}
+
+func writeJSON(w io.Writer, records []record, err error) error {
+	out := struct {
+		Products []jsonRecord  `json:"products"`
+		Errors   []errorRecord `json:"errors"`
+	}{
+		Products: make([]jsonRecord, len(records)),
+		Errors:   errorRecords(err),
+	}
+	for i, r := range records {
+		out.Products[i] = r.json()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeJSONL(w io.Writer, records []record) error {
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r.json()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, records []record) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"domain", "asin", "name", "price", "landed_price", "currency",
+		"availability", "rating", "reviews", "prime", "url",
+	})
+	for _, r := range records {
+		cw.Write([]string{
+			r.Domain,
+			r.ASIN,
+			r.Name,
+			decimal(r.Price),
+			decimal(r.LandedPrice),
+			r.LandedPrice.Currency,
+			r.Availability,
+			strconv.FormatFloat(r.Rating, 'f', -1, 64),
+			strconv.Itoa(r.Reviews),
+			strconv.FormatBool(r.Prime),
+			r.URL,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTable writes the records as an aligned table, from the
+// cheapest to the most expensive landed price.
+func writeTable(w io.Writer, records []record) error {
+	sorted := append([]record{}, records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LandedPrice.Amount < sorted[j].LandedPrice.Amount
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LANDED PRICE\tPRICE\tDOMAIN\tAVAILABILITY\tNAME\tURL")
+	for _, r := range sorted {
+		fmt.Fprintf(tw, "%v\t%v\t%s\t%s\t%s\t%s\n",
+			r.LandedPrice, r.Price, orDash(r.Domain), orDash(r.Availability),
+			truncate(r.Name, maxNameWidth), r.URL)
+	}
+	return tw.Flush()
+}
+
+// errorRecords flattens the search errors, one record for each product
+// that failed, classifying them.
+func errorRecords(err error) []errorRecord {
+	records := []errorRecord{}
+	if err == nil {
+		return records
+	}
+
+	for _, err := range flattenErrors(err) {
+		rec := errorRecord{Kind: errorKind(err), Error: err.Error()}
+
+		var urlErr *product.URLError
+		if errors.As(err, &urlErr) {
+			rec.URL = urlErr.URL
+			rec.Error = urlErr.Err.Error()
+		}
+		var statusErr *fetch.StatusError
+		var captchaErr *fetch.CaptchaError
+		if errors.As(err, &statusErr) {
+			rec.Status = statusErr.StatusCode
+		} else if errors.As(err, &captchaErr) {
+			rec.Status = captchaErr.StatusCode
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// flattenErrors returns the errors inside the product.Errors of the
+// error, recursively, since searches of multiple domains wrap the
+// errors of each domain. Other errors are returned as they are.
+func flattenErrors(err error) []error {
+	var errs product.Errors
+	if !errors.As(err, &errs) {
+		return []error{err}
+	}
+
+	flat := []error{}
+	for _, err := range errs {
+		flat = append(flat, flattenErrors(err)...)
+	}
+	return flat
+}
+
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, fetch.ErrCaptcha):
+		return "captcha"
+	case errors.Is(err, fetch.ErrRateLimited):
+		return "rate-limited"
+	case errors.Is(err, fetch.ErrNotFound):
+		return "not-found"
+	case errors.Is(err, product.ErrParse):
+		return "parse"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return "network"
+	}
+	return "other"
+}
+
+// decimal formats the money as a decimal, without the currency.
+func decimal(m money.Money) string {
+	return strings.TrimSuffix(m.String(), " "+m.Currency)
+}
+
+// truncate truncates the string to max characters, with an ellipsis.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+)
+
+var testRecords = []record{
+	{
+		Domain:       "www.amazon.de",
+		ASIN:         "B08HR7SV3M",
+		Name:         "ZOTAC Gaming GeForce RTX 3080",
+		URL:          "https://www.amazon.de/dp/B08HR7SV3M",
+		Price:        money.Money{Amount: 103456, Currency: "EUR"},
+		LandedPrice:  money.Money{Amount: 103456, Currency: "EUR"},
+		Availability: "in-stock",
+		Rating:       4.6,
+		Reviews:      120,
+		Prime:        true,
+	},
+	{
+		Domain:      "www.amazon.com",
+		ASIN:        "B08KWLMZV4",
+		Name:        "MSI Gaming GeForce RTX 3070 256-Bit HDMI/DP 8GB GDRR6 HDCP Support DirectX 12 VR Ready OC Graphics Card",
+		URL:         "https://www.amazon.com/dp/B08KWLMZV4",
+		Price:       money.Money{Amount: 59999, Currency: "USD"},
+		LandedPrice: money.Money{Amount: 67498, Currency: "USD"},
+	},
+}
+
+func TestWriteJSON(t *testing.T) {
+	errs := product.Errors{
+		&product.URLError{
+			URL: "https://www.amazon.com/dp/B08L8L9TCZ",
+			Err: &fetch.CaptchaError{URL: "https://www.amazon.com/dp/B08L8L9TCZ", StatusCode: 200},
+		},
+		&product.URLError{
+			URL: "https://www.amazon.com/dp/B08HBJB7YD",
+			Err: &fetch.StatusError{URL: "https://www.amazon.com/dp/B08HBJB7YD", StatusCode: 404},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := writeJSON(buf, testRecords, errs); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Products []map[string]interface{} `json:"products"`
+		Errors   []errorRecord            `json:"errors"`
+	}
+	dec := json.NewDecoder(buf)
+	dec.UseNumber()
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Products) != len(testRecords) {
+		t.Fatalf("got %d products; want %d", len(got.Products), len(testRecords))
+	}
+	first := got.Products[0]
+	if first["price"] != json.Number("1034.56") || first["landed_price"] != json.Number("1034.56") {
+		t.Errorf("got price %v landed price %v; want 1034.56", first["price"], first["landed_price"])
+	}
+	if first["currency"] != "EUR" {
+		t.Errorf("got currency %v; want EUR", first["currency"])
+	}
+	if first["asin"] != "B08HR7SV3M" || first["domain"] != "www.amazon.de" {
+		t.Errorf("got product %v; want %v", first, testRecords[0])
+	}
+	if got.Products[1]["landed_price"] != json.Number("674.98") {
+		t.Errorf("got landed price %v; want 674.98", got.Products[1]["landed_price"])
+	}
+
+	wantErrs := []errorRecord{
+		{
+			URL:    "https://www.amazon.com/dp/B08L8L9TCZ",
+			Kind:   "captcha",
+			Status: 200,
+			Error:  errs[0].(*product.URLError).Err.Error(),
+		},
+		{
+			URL:    "https://www.amazon.com/dp/B08HBJB7YD",
+			Kind:   "not-found",
+			Status: 404,
+			Error:  errs[1].(*product.URLError).Err.Error(),
+		},
+	}
+	if !reflect.DeepEqual(got.Errors, wantErrs) {
+		t.Errorf("got errors %+v; want %+v", got.Errors, wantErrs)
+	}
+}
+
+func TestWriteJSONEmpty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := writeJSON(buf, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty lists, not null, so consumers can always iterate them
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"products", "errors"} {
+		if string(got[field]) != "[]" {
+			t.Errorf("got %s %s; want []", field, got[field])
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := writeCSV(buf, testRecords); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"domain,asin,name,price,landed_price,currency,availability,rating,reviews,prime,url",
+		"www.amazon.de,B08HR7SV3M,ZOTAC Gaming GeForce RTX 3080,1034.56,1034.56,EUR,in-stock,4.6,120,true,https://www.amazon.de/dp/B08HR7SV3M",
+		"www.amazon.com,B08KWLMZV4,MSI Gaming GeForce RTX 3070 256-Bit HDMI/DP 8GB GDRR6 HDCP Support DirectX 12 VR Ready OC Graphics Card,599.99,674.98,USD,,0,0,false,https://www.amazon.com/dp/B08KWLMZV4",
+	}, "\n") + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got CSV:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := writeTable(buf, testRecords); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines; want header and 2 records:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "LANDED PRICE") {
+		t.Errorf("got header %q", lines[0])
+	}
+
+	// Sorted by the landed price, cheapest first
+	if !strings.HasPrefix(lines[1], "674.98 USD") {
+		t.Errorf("got first record %q; want the cheapest, 674.98 USD", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "1034.56 EUR") {
+		t.Errorf("got second record %q; want 1034.56 EUR", lines[2])
+	}
+
+	// Missing availability is a dash and long names are truncated
+	if fields := strings.Fields(lines[1]); fields[5] != "-" {
+		t.Errorf("got availability %q; want -", fields[5])
+	}
+	name := truncate(testRecords[1].Name, maxNameWidth)
+	if !strings.Contains(lines[1], name) || strings.Contains(lines[1], testRecords[1].Name) {
+		t.Errorf("got record %q; want the name truncated to %q", lines[1], name)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	type Test struct {
+		s    string
+		max  int
+		want string
+	}
+
+	tests := []Test{
+		{s: "RTX 3080", max: 10, want: "RTX 3080"},
+		{s: "RTX 3080", max: 8, want: "RTX 3080"},
+		{s: "GeForce RTX 3080", max: 8, want: "GeForce…"},
+		{s: "Grafikkarte für Gamer", max: 16, want: "Grafikkarte für…"},
+	}
+
+	for _, test := range tests {
+		if got := truncate(test.s, test.max); got != test.want {
+			t.Errorf("truncate(%q, %d) = %q; want %q", test.s, test.max, got, test.want)
+		}
+	}
+}
+
+func TestErrorRecords(t *testing.T) {
+	type Test struct {
+		name string
+		err  error
+		want []errorRecord
+	}
+
+	parseErr := &product.ParseError{URL: "https://www.amazon.com/dp/B08HBJB7YD", Field: "price"}
+	rateLimited := &fetch.StatusError{URL: "https://www.amazon.com/s", StatusCode: 503}
+	captcha := &fetch.CaptchaError{URL: "https://www.amazon.de/dp/B08L8L9TCZ", StatusCode: 503}
+	notFound := &fetch.StatusError{URL: "https://www.amazon.de/dp/B08KWLMZV4", StatusCode: 404}
+
+	tests := []Test{
+		{
+			name: "NoError",
+			want: []errorRecord{},
+		},
+		{
+			name: "SingleError",
+			err:  rateLimited,
+			want: []errorRecord{
+				{Kind: "rate-limited", Status: 503, Error: rateLimited.Error()},
+			},
+		},
+		{
+			name: "ProductErrors",
+			err: product.Errors{
+				&product.URLError{URL: parseErr.URL, Err: parseErr},
+				&product.URLError{URL: "https://www.amazon.com/dp/B08L8L9TCZ", Err: context.Canceled},
+				errors.New("something else"),
+			},
+			want: []errorRecord{
+				{URL: parseErr.URL, Kind: "parse", Error: parseErr.Error()},
+				{URL: "https://www.amazon.com/dp/B08L8L9TCZ", Kind: "canceled", Error: context.Canceled.Error()},
+				{Kind: "other", Error: "something else"},
+			},
+		},
+		{
+			name: "MultipleDomains",
+			err: product.Errors{
+				fmt.Errorf("domain %q : %w", "www.amazon.de", product.Errors{
+					&product.URLError{URL: captcha.URL, Err: captcha},
+					&product.URLError{URL: notFound.URL, Err: notFound},
+				}),
+				fmt.Errorf("domain %q : %w", "www.amazon.com", rateLimited),
+			},
+			want: []errorRecord{
+				{URL: captcha.URL, Kind: "captcha", Status: 503, Error: captcha.Error()},
+				{URL: notFound.URL, Kind: "not-found", Status: 404, Error: notFound.Error()},
+				{
+					Kind:   "rate-limited",
+					Status: 503,
+					Error:  fmt.Sprintf("domain %q : %v", "www.amazon.com", rateLimited),
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			got := errorRecords(test.err)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v; want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -1,58 +1,319 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/katcipis/amazoner/chromedriver"
+	"github.com/katcipis/amazoner/cli"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/header"
+	"github.com/katcipis/amazoner/interrupt"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/pricehistory"
 	"github.com/katcipis/amazoner/product"
 	"github.com/katcipis/amazoner/search"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run searches the products, returning the exit code, so the
+// deferred calls save the cache and cookies before exiting.
+func run() int {
 	var (
-		domain   string
-		name     string
-		minPrice uint
-		maxPrice uint
-		filter   bool
+		domain    string
+		query     = search.Query{MaxPrice: money.Money{Amount: 1000000}}
+		sort      string
+		condition string
+		filter    bool
+		timeout   time.Duration
+		workers   int
+		rate      time.Duration
+		cache     string
+		period    time.Duration
+		history   string
+		deal      float64
+		currency  string
+		ratesPath string
+		captcha   bool
+		cookies   string
+		profile   string
+		retries   int
+		proxies   string
+		output    string
 	)
 
-	flag.StringVar(&domain, "domain", "www.amazon.com", "Amazon domain to search")
-	flag.StringVar(&name, "name", "", "name of product")
-	flag.UintVar(&minPrice, "min", 0, "min price of product")
-	flag.UintVar(&maxPrice, "max", 10000, "max price of product")
+	flag.StringVar(&domain, "domain", "www.amazon.com", "Amazon domain to search, multiple domains are comma separated, like www.amazon.com,www.amazon.de")
+	flag.StringVar(&query.Keywords, "name", "", "name of product")
+	flag.Var(&query.MinPrice, "min", "min price of product")
+	flag.Var(&query.MaxPrice, "max", "max price of product")
+	flag.StringVar(&sort, "sort", "", "sort results by: price-asc, price-desc, newest, reviews, bestsellers (default relevance)")
+	flag.StringVar(&query.Department, "department", "", "department to search, like computers or electronics")
+	flag.BoolVar(&query.PrimeOnly, "prime", false, "only Prime eligible products, supported only on www.amazon.com")
+	flag.StringVar(&condition, "condition", "", "condition of products: new, used or renewed, supported only on www.amazon.com")
+	flag.StringVar(&query.Brand, "brand", "", "brand of products")
+	flag.StringVar(&query.Seller, "seller", "", "ID of the seller of products")
 	flag.BoolVar(&filter, "filter", false, "filter results")
+	flag.DurationVar(&timeout, "timeout", 0, "max duration of the search, zero means no limit")
+	flag.IntVar(&workers, "workers", product.DefaultWorkers, "how many products are fetched concurrently")
+	flag.DurationVar(&rate, "rate", time.Second, "min interval between requests to Amazon")
+	flag.IntVar(&retries, "retries", fetch.DefaultRetry.Attempts, "max attempts of requests failing with transient errors, like status 503")
+	flag.StringVar(&cache, "cache", "", "path of a file to cache products across runs")
+	flag.DurationVar(&period, "cache-period", time.Hour, "how long products are cached")
+	flag.StringVar(&history, "history", "", "path of a file to record the prices of the products")
+	flag.Float64Var(&deal, "deal", 10, "min percentage below the 30 days average price to report a deal, requires -history")
+	flag.IntVar(&query.MaxPages, "pages", 1, "max number of search results pages")
+	flag.IntVar(&query.MaxResults, "max-results", 0, "max number of products, zero means no limit")
+	flag.StringVar(&currency, "currency", "", "currency to compare prices of multiple domains, defaults to the base currency of -rates")
+	flag.StringVar(&cookies, "cookies", "", "path of a file to keep the cookies of Amazon across runs")
+	flag.StringVar(&proxies, "proxies", "", "path of a file with the proxies of the requests, one URL per line, like socks5://10.0.0.2:1080")
+	flag.StringVar(&profile, "profile", "", "browser profile of the requests, like chrome-linux or firefox-windows (default random)")
+	flag.BoolVar(&captcha, "solve-captcha", false, "open captcha challenges on chrome to be solved manually")
+	flag.StringVar(&output, "output", outputText, "output format of the results: "+strings.Join(outputs, ", "))
+	flag.StringVar(&ratesPath, "rates", "", `path of a JSON exchange rates file, like {"base": "EUR", "rates": {"USD": 0.82, "GBP": 1.12}}`)
 
 	flag.Parse()
 
-	if name == "" {
+	if query.Keywords == "" {
 		fmt.Println("name is an obligatory parameter")
-		os.Exit(1)
-		return
+		return 1
+	}
+
+	var ok bool
+	if query.Sort, ok = sorts[sort]; !ok {
+		fmt.Printf("unknown sort %q\n", sort)
+		return 1
+	}
+	query.Condition = search.Condition(condition)
+
+	if !validOutput(output) {
+		fmt.Printf("unknown output %q\n", output)
+		return 1
+	}
+
+	// On the other formats stdout has only the results,
+	// so it can be parsed, and the rest goes to stderr
+	var info io.Writer = os.Stdout
+	if output != outputText {
+		info = os.Stderr
+	}
+
+	fmt.Fprintf(info, "search product %q min price %v max price %v\n\n", query.Keywords, query.MinPrice, query.MaxPrice)
+
+	ctx, cancel := interrupt.Context()
+	defer cancel()
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	searcher := search.New(period)
+	if cache != "" {
+		fileCache, err := search.OpenFileCache(cache)
+		if err != nil {
+			fmt.Printf("unable to open cache %q : %v\n", cache, err)
+			return 1
+		}
+		defer fileCache.Close()
+
+		searcher = search.NewWithCache(period, fileCache)
 	}
+	jar, err := cli.OpenCookies(cookies)
+	if err != nil {
+		fmt.Printf("unable to open cookies %q : %v\n", cookies, err)
+		return 1
+	}
+	defer cli.SaveCookies(jar)
+
+	retry := fetch.DefaultRetry
+	retry.Attempts = retries
+
+	pool, err := cli.OpenProxies(proxies)
+	if err != nil {
+		fmt.Printf("unable to open proxies %q : %v\n", proxies, err)
+		return 1
+	}
+
+	searcher.Client = &fetch.Client{Limiter: fetch.NewLimiter(rate, 1), Jar: jar, Retry: &retry, Proxies: pool}
+	if profile != "" {
+		p, ok := header.Lookup(profile)
+		if !ok {
+			fmt.Printf("unknown profile %q\n", profile)
+			return 1
+		}
+		searcher.Client.Profile = p
+	}
+	if captcha {
+		searcher.Client.CaptchaSolver = &chromedriver.ManualSolver{}
+	}
+	searcher.Workers = workers
+
+	domains := strings.Split(domain, ",")
+	for i := range domains {
+		domains[i] = strings.TrimSpace(domains[i])
+	}
+	if len(domains) > 1 || ratesPath != "" {
+		results, err := compareDomains(ctx, searcher, domains, query, currency, ratesPath)
+
+		if output == outputText {
+			fmt.Println("==== RESULTS START ====")
+			for _, res := range results {
+				fmt.Printf("%v (price %v) %s: %s %s\n",
+					res.LandedPrice, res.Product.Price, res.Domain, res.Product.Name, res.Product.URL)
+			}
+			fmt.Println("==== RESULTS END ====")
+		} else {
+			records := make([]record, len(results))
+			for i, res := range results {
+				records[i] = resultRecord(res)
+			}
+			printRecords(output, records, err)
+		}
 
-	fmt.Printf("search product %q min price %d max price %d\n\n", name, minPrice, maxPrice)
+		if err != nil {
+			printErrors(output, err)
+			if len(results) == 0 {
+				return 1
+			}
+		}
+		return 0
+	}
 
-	searcher := search.New(time.Second)
-	products, err := searcher.Search(domain, name, minPrice, maxPrice)
+	products, err := searcher.SearchContext(ctx, domain, query)
 
 	if filter {
-		products = product.Filter(name, products)
+		products = product.Filter(query.Keywords, products)
 	}
 
-	fmt.Println("==== RESULTS START ====")
-	for _, prod := range products {
-		fmt.Printf("%+v\n", prod)
+	if output == outputText {
+		fmt.Println("==== RESULTS START ====")
+		for _, prod := range products {
+			fmt.Printf("%+v\n", prod)
+		}
+		fmt.Println("==== RESULTS END ====")
+	} else {
+		records := make([]record, len(products))
+		for i, prod := range products {
+			records[i] = productRecord(prod)
+		}
+		printRecords(output, records, err)
+	}
+
+	if history != "" {
+		if err := recordPrices(info, history, products, deal); err != nil {
+			logerr(fmt.Sprintf("unable to record price history : %v", err))
+		}
 	}
-	fmt.Println("==== RESULTS END ====")
 
 	if err != nil {
-		logerr("==== ERRORS START ====")
-		logerr(err.Error())
-		logerr("==== ERRORS END ====")
+		printErrors(output, err)
+		if len(products) == 0 {
+			return 1
+		}
 	}
+	return 0
+}
+
+// compareDomains searches all the domains, returning the products of
+// all of them from the cheapest to the most expensive landed price.
+func compareDomains(ctx context.Context, searcher *search.Searcher, domains []string, query search.Query, currency, ratesPath string) ([]search.Result, error) {
+	rates := money.Rates{}
+	if ratesPath != "" {
+		var err error
+		if rates, err = money.LoadRates(ratesPath); err != nil {
+			return nil, fmt.Errorf("unable to load exchange rates %q : %v", ratesPath, err)
+		}
+	}
+	if currency == "" {
+		currency = rates.Base
+	}
+	if currency == "" {
+		return nil, errors.New("currency or rates is required to compare multiple domains")
+	}
+
+	return searcher.SearchDomainsContext(ctx, domains, query, currency, rates)
+}
+
+// printRecords prints the records on stdout on the given output format.
+func printRecords(output string, records []record, err error) {
+	if err := writeRecords(os.Stdout, output, records, err); err != nil {
+		logerr(fmt.Sprintf("unable to write results : %v", err))
+	}
+}
+
+// printErrors prints the errors of the search on stderr, except on the
+// JSON output, which already has them.
+func printErrors(output string, err error) {
+	if output == outputJSON {
+		return
+	}
+	logerr("==== ERRORS START ====")
+	logerr(err.Error())
+	logerr("==== ERRORS END ====")
+}
+
+func validOutput(output string) bool {
+	for _, o := range outputs {
+		if o == output {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPrices records the prices of the products on the price
+// history, printing the products whose price is a deal on w.
+func recordPrices(w io.Writer, path string, products []product.Product, minPercent float64) error {
+	store, err := pricehistory.Open(path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	const window = 30 * 24 * time.Hour
+
+	now := time.Now()
+	deals := []pricehistory.Deal{}
+
+	for _, prod := range products {
+		if err := store.Record(prod, now); err != nil {
+			logerr(fmt.Sprintf("unable to record price of %q : %v", prod.URL, err))
+			continue
+		}
+
+		key, _ := pricehistory.KeyOf(prod)
+		if deal, ok, err := store.CheckDeal(key, window, minPercent); err == nil && ok {
+			deals = append(deals, deal)
+		}
+	}
+
+	fmt.Fprintln(w, "==== DEALS START ====")
+	for _, deal := range deals {
+		fmt.Fprintf(w, "%s: %v is %.1f%% below the 30 days average %v\n",
+			deal.Current.Name, deal.Current.Price, deal.Percent, deal.Average)
+	}
+	fmt.Fprintln(w, "==== DEALS END ====")
+
+	return nil
+}
+
+var sorts = map[string]search.Sort{
+	"":            search.SortRelevance,
+	"relevance":   search.SortRelevance,
+	"price-asc":   search.SortPriceAsc,
+	"price-desc":  search.SortPriceDesc,
+	"newest":      search.SortNewest,
+	"reviews":     search.SortReviews,
+	"bestsellers": search.SortBestSellers,
 }
 
 func logerr(msg string) {
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/katcipis/amazoner/cli"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/interrupt"
+	"github.com/katcipis/amazoner/watch"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// run watches the targets, returning the exit code, so the
+// cookies are saved before exiting even on failures.
+func run() int {
+	var (
+		config  string
+		rate    time.Duration
+		once    bool
+		cookies string
+		retries int
+		proxies string
+	)
+
+	flag.StringVar(&config, "config", "", "path of the JSON watchlist config")
+	flag.DurationVar(&rate, "rate", time.Second, "min interval between requests to Amazon")
+	flag.IntVar(&retries, "retries", fetch.DefaultRetry.Attempts, "max attempts of requests failing with transient errors, like status 503")
+	flag.BoolVar(&once, "once", false, "check the watchlist once and exit")
+	flag.StringVar(&cookies, "cookies", "", "path of a file to keep the cookies of Amazon across runs")
+	flag.StringVar(&proxies, "proxies", "", "path of a file with the proxies of the requests, one URL per line, like socks5://10.0.0.2:1080")
+
+	flag.Parse()
+
+	if config == "" {
+		fmt.Println("config is an obligatory parameter")
+		return 1
+	}
+
+	cfg, err := watch.LoadConfig(config)
+	if err != nil {
+		fmt.Printf("unable to load config %q : %v\n", config, err)
+		return 1
+	}
+
+	watcher, err := cfg.Watcher()
+	if err != nil {
+		fmt.Printf("unable to load config %q : %v\n", config, err)
+		return 1
+	}
+	jar, err := cli.OpenCookies(cookies)
+	if err != nil {
+		fmt.Printf("unable to open cookies %q : %v\n", cookies, err)
+		return 1
+	}
+	defer cli.SaveCookies(jar)
+
+	retry := fetch.DefaultRetry
+	retry.Attempts = retries
+
+	pool, err := cli.OpenProxies(proxies)
+	if err != nil {
+		fmt.Printf("unable to open proxies %q : %v\n", proxies, err)
+		return 1
+	}
+
+	watcher.Client = &fetch.Client{Limiter: fetch.NewLimiter(rate, 1), Jar: jar, Retry: &retry, Proxies: pool}
+	watcher.OnError = func(err error) {
+		logerr(fmt.Sprintf("%s %v", time.Now().Format("2006-01-02 15:04:05"), err))
+	}
+
+	ctx, cancel := interrupt.Context()
+	defer cancel()
+
+	if once {
+		if _, err := watcher.Check(ctx); err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("watching %d targets\n", len(watcher.Targets))
+	if err := watcher.Run(ctx); err != nil && err != context.Canceled {
+		logerr(err.Error())
+		return 1
+	}
+	return 0
+}
+
+func logerr(msg string) {
+	fmt.Fprintln(os.Stderr, msg)
+}
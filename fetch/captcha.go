@@ -0,0 +1,133 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// CaptchaSolver solves captcha challenges, returning the cookies
+// that allow the requests to go on without new challenges.
+type CaptchaSolver interface {
+	// Solve solves the captcha challenge got on the given link. If the
+	// request went through a proxy it is on the context, see ProxyFromContext.
+	Solve(ctx context.Context, link string) ([]*http.Cookie, error)
+}
+
+// CaptchaSolverFunc is a function that is a CaptchaSolver.
+type CaptchaSolverFunc func(ctx context.Context, link string) ([]*http.Cookie, error)
+
+func (f CaptchaSolverFunc) Solve(ctx context.Context, link string) ([]*http.Cookie, error) {
+	return f(ctx, link)
+}
+
+// CaptchaError is a captcha challenge, or other bot detection page,
+// got instead of the requested page. It is ErrCaptcha when checked with
+// errors.Is, and also ErrRateLimited if it came with status 429 or 503.
+type CaptchaError struct {
+	URL        string
+	StatusCode int
+	// Proxy is the proxy the request went through, if any.
+	Proxy *url.URL
+}
+
+func (e *CaptchaError) Error() string {
+	return fmt.Sprintf("url %q %s (status %d)", e.URL, ErrCaptcha, e.StatusCode)
+}
+
+// Is reports whether the captcha matches the target error.
+func (e *CaptchaError) Is(target error) bool {
+	if target == ErrCaptcha {
+		return true
+	}
+	status := &StatusError{StatusCode: e.StatusCode}
+	return status.Is(target)
+}
+
+// captchaMarkers are found only on the captcha form.
+var captchaMarkers = [][]byte{
+	[]byte(`/errors/validatecaptcha`),
+	[]byte(`id="captchacharacters"`),
+}
+
+// robotCheckTitle is the title of older captcha pages.
+var robotCheckTitle = regexp.MustCompile(`<title[^>]*>\s*robot check\s*</title>`)
+
+// dogsMarkers are found on the 503 "Sorry! Something went wrong!"
+// page, with the dogs of Amazon, sent when there are too many requests.
+var dogsMarkers = [][]byte{
+	[]byte(`/dogsofamazon`),
+	[]byte(`dogs of amazon`),
+}
+
+// IsCaptcha reports whether the body is a captcha challenge or other
+// bot detection page that must be solved, whatever the status it came
+// with. The dogs page is not a captcha, see IsRateLimitPage.
+func IsCaptcha(body []byte) bool {
+	lower := bytes.ToLower(body)
+	return containsAny(lower, captchaMarkers) || robotCheckTitle.Match(lower)
+}
+
+// IsRateLimitPage reports whether the body is the "Sorry! Something
+// went wrong!" page, with the dogs of Amazon, sent with status 503 when
+// there are too many requests. It goes away by itself, so the request
+// just has to be retried later, as any other rate limited request.
+func IsRateLimitPage(body []byte) bool {
+	return containsAny(bytes.ToLower(body), dogsMarkers)
+}
+
+// solveCaptcha solves the captcha got on the link, keeping the cookies
+// of the solution on the jar for the next requests. Only one captcha is
+// solved at a time, if another captcha was solved since the request was
+// sent the captcha is considered solved, the request is just sent again.
+func (c *Client) solveCaptcha(ctx context.Context, link string, sent int) error {
+	c.solveMu.Lock()
+	defer c.solveMu.Unlock()
+
+	if c.solvedCaptchas() != sent {
+		return nil
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+
+	cookies, err := c.CaptchaSolver.Solve(ctx, link)
+	if err != nil {
+		return err
+	}
+
+	_, jar := c.session()
+	jar.SetCookies(u, cookies)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.solved++
+	return nil
+}
+
+// solvedCaptchas returns how many captchas were solved.
+func (c *Client) solvedCaptchas() int {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.solved
+}
+
+func containsAny(s []byte, markers [][]byte) bool {
+	for _, marker := range markers {
+		if bytes.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
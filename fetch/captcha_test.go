@@ -0,0 +1,186 @@
+package fetch_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/fetch"
+)
+
+const (
+	captchaPage = `<html><head><title dir="ltr">Amazon.com</title></head><body>
+<form method="get" action="/errors/validateCaptcha">
+<input id="captchacharacters" name="field-keywords" type="text">
+</form></body></html>`
+
+	robotCheckPage = `<html><head><title>Robot Check</title></head><body>
+<p>Sorry, we just need to make sure you're not a robot.</p></body></html>`
+
+	dogsPage = `<html><head><title>Sorry! Something went wrong!</title></head><body>
+<a href="/dogsofamazon"><img alt="Dogs of Amazon"></a></body></html>`
+
+	productPage = `<html><head><title>Amazon.com: Captcha Solving for Dummies</title></head>
+<body><span id="productTitle">Captcha Solving for Dummies</span></body></html>`
+)
+
+func TestIsCaptcha(t *testing.T) {
+	type Test struct {
+		name string
+		body string
+		want bool
+	}
+
+	tests := []Test{
+		{name: "CaptchaForm", body: captchaPage, want: true},
+		{name: "RobotCheck", body: robotCheckPage, want: true},
+		{name: "Dogs", body: dogsPage, want: false},
+		{name: "ProductAboutCaptchas", body: productPage, want: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if got := fetch.IsCaptcha([]byte(test.body)); got != test.want {
+				t.Errorf("got captcha %t; want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetCaptcha(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/captcha":
+			w.Write([]byte(captchaPage))
+		case "/robot":
+			w.Write([]byte(robotCheckPage))
+		}
+	}))
+	defer server.Close()
+
+	client := &fetch.Client{Limiter: fetch.NewLimiter(0, 1)}
+
+	for _, path := range []string{"/captcha", "/robot"} {
+		_, err := client.Get(server.URL + path)
+		if !errors.Is(err, fetch.ErrCaptcha) {
+			t.Errorf("%s: got err %v; want %v", path, err, fetch.ErrCaptcha)
+		}
+
+		var captchaErr *fetch.CaptchaError
+		if !errors.As(err, &captchaErr) {
+			t.Fatalf("%s: got err %T; want %T", path, err, captchaErr)
+		}
+		if captchaErr.URL != server.URL+path {
+			t.Errorf("%s: got URL %q; want %q", path, captchaErr.URL, server.URL+path)
+		}
+		if errors.Is(err, fetch.ErrRateLimited) {
+			t.Errorf("%s: got rate limited %v; want only %v", path, err, fetch.ErrCaptcha)
+		}
+	}
+}
+
+func TestGetRateLimitPage(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(dogsPage))
+	}))
+	defer server.Close()
+
+	solver := fetch.CaptchaSolverFunc(func(ctx context.Context, link string) ([]*http.Cookie, error) {
+		t.Fatal("the rate limit page must not be solved as a captcha")
+		return nil, nil
+	})
+	client := &fetch.Client{
+		Limiter:       fetch.NewLimiter(0, 1),
+		Retry:         &fetch.Retry{Attempts: 2, MinBackoff: time.Millisecond},
+		CaptchaSolver: solver,
+	}
+
+	_, err := client.Get(server.URL)
+	if errors.Is(err, fetch.ErrCaptcha) {
+		t.Fatalf("got err %v; want it not to be %v", err, fetch.ErrCaptcha)
+	}
+	if !errors.Is(err, fetch.ErrRateLimited) {
+		t.Errorf("got err %v; want %v", err, fetch.ErrRateLimited)
+	}
+
+	var statusErr *fetch.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got err %v; want %T with status %d", err, statusErr, http.StatusServiceUnavailable)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests; want the 2 attempts", requests)
+	}
+}
+
+func TestIsRateLimitPage(t *testing.T) {
+	if !fetch.IsRateLimitPage([]byte(dogsPage)) {
+		t.Error("want the dogs page to be the rate limit page")
+	}
+	for _, page := range []string{captchaPage, robotCheckPage, productPage} {
+		if fetch.IsRateLimitPage([]byte(page)) {
+			t.Errorf("got rate limit page for %q", page)
+		}
+	}
+}
+
+func TestCaptchaSolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if cookie, err := req.Cookie("session-token"); err != nil || cookie.Value != "solved" {
+			w.Write([]byte(captchaPage))
+			return
+		}
+		w.Write([]byte(productPage))
+	}))
+	defer server.Close()
+
+	solves := 0
+	client := &fetch.Client{
+		Limiter: fetch.NewLimiter(0, 1),
+		CaptchaSolver: fetch.CaptchaSolverFunc(func(ctx context.Context, link string) ([]*http.Cookie, error) {
+			solves++
+			if link != server.URL+"/dp/B000000000" {
+				t.Errorf("got captcha link %q; want the requested link", link)
+			}
+			return []*http.Cookie{{Name: "session-token", Value: "solved"}}, nil
+		}),
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(server.URL + "/dp/B000000000"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if solves != 1 {
+		t.Errorf("got %d solves; want 1 since the cookies are kept", solves)
+	}
+}
+
+func TestCaptchaSolverFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(captchaPage))
+	}))
+	defer server.Close()
+
+	client := &fetch.Client{
+		Limiter: fetch.NewLimiter(0, 1),
+		CaptchaSolver: fetch.CaptchaSolverFunc(func(ctx context.Context, link string) ([]*http.Cookie, error) {
+			return nil, errors.New("nobody solved it")
+		}),
+	}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, fetch.ErrCaptcha) {
+		t.Errorf("got err %v; want %v", err, fetch.ErrCaptcha)
+	}
+	if err == nil || !strings.Contains(err.Error(), "nobody solved it") {
+		t.Errorf("got err %v; want the solver error on it", err)
+	}
+}
@@ -0,0 +1,180 @@
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CookieJar is an http.CookieJar that can be saved to a file, so the
+// sessions started with Amazon, and the captchas solved, are kept
+// between runs. It is safe for concurrent use.
+type CookieJar struct {
+	path string
+	jar  *cookiejar.Jar
+
+	mu sync.Mutex
+	// cookies has the cookies set on each origin, by name, domain and path
+	cookies map[string]map[string]savedCookie
+}
+
+// savedCookie is a cookie on the file of the jar.
+type savedCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Path     string    `json:"path,omitempty"`
+	Domain   string    `json:"domain,omitempty"`
+	Expires  time.Time `json:"expires"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+// NewCookieJar creates an in memory cookie jar, Save does nothing.
+func NewCookieJar() *CookieJar {
+	jar, _ := cookiejar.New(nil)
+	return &CookieJar{jar: jar, cookies: map[string]map[string]savedCookie{}}
+}
+
+// OpenCookieJar opens the cookie jar saved on the given path, creating an
+// empty jar if the file doesn't exist. The cookies are saved on the same
+// path by Save. Expired cookies are dropped.
+func OpenCookieJar(path string) (*CookieJar, error) {
+	j := NewCookieJar()
+	j.path = path
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cookie jar %q : %v", path, err)
+	}
+
+	saved := map[string][]savedCookie{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("parsing cookie jar %q : %v", path, err)
+	}
+
+	now := time.Now()
+	for origin, cookies := range saved {
+		u, err := url.Parse(origin)
+		if err != nil {
+			continue
+		}
+		httpCookies := []*http.Cookie{}
+		for _, c := range cookies {
+			if !c.Expires.IsZero() && c.Expires.Before(now) {
+				continue
+			}
+			httpCookies = append(httpCookies, c.httpCookie())
+		}
+		j.SetCookies(u, httpCookies)
+	}
+	return j, nil
+}
+
+// SetCookies sets the cookies of a response from the given URL.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	origin := u.Scheme + "://" + u.Host
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	saved, ok := j.cookies[origin]
+	if !ok {
+		saved = map[string]savedCookie{}
+		j.cookies[origin] = saved
+	}
+
+	for _, c := range cookies {
+		key := c.Name + ";" + c.Domain + ";" + c.Path
+		if c.MaxAge < 0 {
+			delete(saved, key)
+			continue
+		}
+		sc := savedCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if c.MaxAge > 0 {
+			sc.Expires = time.Now().Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		saved[key] = sc
+	}
+}
+
+// Cookies returns the cookies to send on a request to the given URL.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save saves the cookies to the file the jar was opened from,
+// replacing it. Jars created with NewCookieJar are not saved.
+func (j *CookieJar) Save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	j.mu.Lock()
+	now := time.Now()
+	saved := map[string][]savedCookie{}
+	for origin, cookies := range j.cookies {
+		for _, c := range cookies {
+			if !c.Expires.IsZero() && c.Expires.Before(now) {
+				continue
+			}
+			saved[origin] = append(saved[origin], c)
+		}
+	}
+	j.mu.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Written to a temporary file first, so a failure doesn't lose the jar
+	tmp, err := ioutil.TempFile(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return fmt.Errorf("saving cookie jar : %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("saving cookie jar : %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("saving cookie jar : %v", err)
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("saving cookie jar : %v", err)
+	}
+	return nil
+}
+
+func (c savedCookie) httpCookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Domain:   c.Domain,
+		Expires:  c.Expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+}
@@ -0,0 +1,118 @@
+package fetch_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/fetch"
+)
+
+func TestGetKeepsCookies(t *testing.T) {
+	server := newSessionServer(t)
+
+	client := &fetch.Client{Limiter: fetch.NewLimiter(0, 1)}
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.sessions; got != 1 {
+		t.Errorf("got %d sessions; want 1 since the session cookie is kept", got)
+	}
+}
+
+func TestCookieJarSave(t *testing.T) {
+	server := newSessionServer(t)
+	path := filepath.Join(t.TempDir(), "cookies.json")
+
+	for run := 0; run < 2; run++ {
+		jar, err := fetch.OpenCookieJar(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &fetch.Client{Limiter: fetch.NewLimiter(0, 1), Jar: jar}
+
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatal(err)
+		}
+		if err := jar.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := server.sessions; got != 1 {
+		t.Errorf("got %d sessions; want 1 since the session cookie is saved", got)
+	}
+}
+
+func TestCookieJarDropsExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	u, _ := url.Parse("https://www.amazon.com")
+
+	jar, err := fetch.OpenCookieJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "session-id", Value: "1", Expires: time.Now().Add(time.Hour)},
+		{Name: "csm-hit", Value: "2", MaxAge: 1},
+		{Name: "skin", Value: "noskin"},
+	})
+	jar.SetCookies(u, []*http.Cookie{{Name: "skin", MaxAge: -1}})
+
+	time.Sleep(1100 * time.Millisecond)
+
+	if err := jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	jar, err = fetch.OpenCookieJar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := jar.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Name != "session-id" {
+		t.Fatalf("got cookies %v; want only session-id", cookies)
+	}
+}
+
+func TestOpenCookieJarCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fetch.OpenCookieJar(path); err == nil {
+		t.Fatal("want error opening corrupted jar")
+	}
+}
+
+type sessionServer struct {
+	*httptest.Server
+	sessions int
+}
+
+// newSessionServer starts a server that starts a new session,
+// setting a session cookie, for requests without one.
+func newSessionServer(t *testing.T) *sessionServer {
+	s := &sessionServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, err := req.Cookie("session-id"); err != nil {
+			s.sessions++
+			http.SetCookie(w, &http.Cookie{
+				Name:    "session-id",
+				Value:   "138-1486552-1785917",
+				Path:    "/",
+				Expires: time.Now().Add(time.Hour),
+			})
+		}
+		w.Write([]byte("page"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
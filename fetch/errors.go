@@ -0,0 +1,69 @@
+package fetch
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+type Error string
+
+const (
+	// ErrCaptcha is a captcha challenge instead of the requested page,
+	// Amazon sends them when it suspects the requests come from a bot.
+	ErrCaptcha Error = "captcha challenge"
+	// ErrRateLimited is a response with status 429 or 503,
+	// which Amazon sends when there are too many requests.
+	ErrRateLimited Error = "rate limited"
+	// ErrNotFound is a response with status 404.
+	ErrNotFound Error = "not found"
+)
+
+// StatusError is a response with an unexpected status. It is ErrNotFound
+// or ErrRateLimited, according to its status, when checked with errors.Is.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	// Body is the start of the body of the response,
+	// truncated to MaxErrorBody bytes.
+	Body string
+	// RetryAfter is the time to wait before retrying the
+	// request, from the Retry-After header of the response.
+	RetryAfter time.Duration
+}
+
+// MaxErrorBody is the max size of the response body kept on errors.
+const MaxErrorBody = 512
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("url %q unexpected status %d; resp body:\n%s", e.URL, e.StatusCode, e.Body)
+}
+
+// Is reports whether the status matches the target error.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests ||
+			e.StatusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+// truncate truncates the body to max bytes, without breaking UTF-8
+// characters, appending "..." if truncated.
+func truncate(body []byte, max int) string {
+	if len(body) <= max {
+		return string(body)
+	}
+	for max > 0 && !utf8.RuneStart(body[max]) {
+		max--
+	}
+	return string(body[:max]) + "..."
+}
+
+func (e Error) Error() string {
+	return string(e)
+}
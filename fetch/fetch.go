@@ -0,0 +1,272 @@
+// Package fetch provides the HTTP client used to get pages from Amazon.
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/katcipis/amazoner/header"
+)
+
+// Client gets pages from Amazon. A nil or zero value Client is ready
+// to use, talking with Amazon through HTTPS with the DefaultTimeout.
+//
+// A Client must not be changed after its first use, but it can be used
+// concurrently.
+type Client struct {
+	// HTTPClient is used to perform the requests, allowing custom
+	// transports for testing, proxies or caching.
+	// Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Scheme is used to build URLs from domains, defaults to "https".
+	Scheme string
+
+	// Timeout limits how long each request can take, including
+	// reading the response body. Defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// Header has headers added to each request, they take
+	// precedence over the default browser like headers.
+	Header http.Header
+
+	// Limiter limits the rate of requests, it can be shared among
+	// clients to have a global rate limit. Defaults to DefaultLimiter.
+	Limiter *Limiter
+
+	// Profile is the browser whose headers are sent on the requests.
+	// If it has no name one of header.Profiles is picked at random on
+	// the first request, and used on all the others.
+	Profile header.Profile
+
+	// Jar keeps the cookies of the responses, sending them on the next
+	// requests. Defaults to the jar of the HTTPClient, or to an in memory
+	// jar for each client. Use a CookieJar opened from a file to keep the
+	// cookies between runs.
+	Jar http.CookieJar
+
+	// Proxies is the pool of proxies the requests go through, it can be
+	// shared among clients. If nil requests go through the proxy of the
+	// HTTPClient, if any. Use WithProxy to pin the proxy of requests.
+	Proxies *ProxyPool
+
+	// Retry is the policy to retry requests that failed because of
+	// transient failures. Defaults to DefaultRetry.
+	Retry *Retry
+
+	// CaptchaSolver solves the captcha challenges got, the request is
+	// sent again with the cookies of the solution. If nil captchas
+	// are returned as a *CaptchaError.
+	CaptchaSolver CaptchaSolver
+
+	mu         sync.Mutex
+	solveMu    sync.Mutex
+	profile    *header.Profile
+	jar        http.CookieJar
+	solved     int
+	transports map[string]*http.Transport
+}
+
+const DefaultTimeout = 30 * time.Second
+
+// URL builds an URL to the given path on the given domain.
+func (c *Client) URL(domain, path string) string {
+	return c.scheme() + "://" + domain + path
+}
+
+// Get gets the page on the given link, returning its contents.
+// Captcha challenges and other bot detection pages are a *CaptchaError,
+// any other response with a status other than 200 is a *StatusError.
+func (c *Client) Get(link string) (io.Reader, error) {
+	return c.GetContext(context.Background(), link)
+}
+
+// GetContext is like Get but with a context that can
+// cancel the request.
+func (c *Client) GetContext(ctx context.Context, link string) (io.Reader, error) {
+	solved := c.solvedCaptchas()
+	body, err := c.getRetrying(ctx, link)
+
+	var captchaErr *CaptchaError
+	if err != nil && c != nil && c.CaptchaSolver != nil && errors.As(err, &captchaErr) {
+		// The captcha is solved, and the request sent again, through the
+		// same proxy, since the solution may be valid only for its IP
+		if captchaErr.Proxy != nil {
+			ctx = WithProxy(ctx, captchaErr.Proxy)
+		}
+		if solveErr := c.solveCaptcha(ctx, link, solved); solveErr != nil {
+			return nil, fmt.Errorf("%w : solving it : %v", err, solveErr)
+		}
+		body, err = c.getRetrying(ctx, link)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+func (c *Client) getRetrying(ctx context.Context, link string) ([]byte, error) {
+	return c.retry().do(ctx, func() ([]byte, error) {
+		return c.get(ctx, link)
+	})
+}
+
+func (c *Client) get(ctx context.Context, link string) ([]byte, error) {
+	if err := c.limiter().Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	proxy := ProxyFromContext(ctx)
+	if proxy == nil && c != nil && c.Proxies != nil {
+		proxy = c.Proxies.Next()
+	}
+
+	body, err := c.send(ctx, link, proxy)
+	if c != nil && c.Proxies != nil && proxy != nil && ctx.Err() == nil {
+		c.Proxies.Report(proxy, err)
+	}
+	return body, err
+}
+
+func (c *Client) send(ctx context.Context, link string, proxy *url.URL) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, jar := c.session()
+	profile.Add(req)
+	setCurrency(req, jar)
+	if c != nil {
+		for name, values := range c.Header {
+			req.Header.Del(name)
+			for _, v := range values {
+				req.Header.Add(name, v)
+			}
+		}
+	}
+
+	httpClient := *c.httpClient()
+	httpClient.Jar = jar
+	if proxy != nil {
+		httpClient.Transport = c.proxyTransport(proxy)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("url %q reading response body : %w", link, err)
+	}
+
+	if IsCaptcha(body) {
+		return nil, &CaptchaError{URL: link, StatusCode: res.StatusCode, Proxy: proxy}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, &StatusError{
+			URL:        link,
+			StatusCode: res.StatusCode,
+			Body:       truncate(body, MaxErrorBody),
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	return body, nil
+}
+
+// setCurrency sets the currency cookie of the marketplace on the jar,
+// unless the jar already has one, so it is sent once. Without a jar
+// it is set on the request.
+func setCurrency(req *http.Request, jar http.CookieJar) {
+	cookie := header.CurrencyCookie(req.URL)
+	if jar == nil {
+		req.AddCookie(cookie)
+		return
+	}
+	for _, c := range jar.Cookies(req.URL) {
+		if c.Name == cookie.Name {
+			return
+		}
+	}
+	jar.SetCookies(req.URL, []*http.Cookie{cookie})
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c == nil || c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// session returns the browser profile and the cookie jar of the client,
+// choosing them on the first use. A nil client has no cookie jar.
+func (c *Client) session() (header.Profile, http.CookieJar) {
+	if c == nil {
+		return header.DefaultProfile, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.profile == nil {
+		profile := c.Profile
+		if profile.Name == "" {
+			profile = header.Random()
+		}
+		c.profile = &profile
+
+		c.jar = c.Jar
+		if c.jar == nil {
+			c.jar = c.httpClient().Jar
+		}
+		if c.jar == nil {
+			c.jar = NewCookieJar()
+		}
+	}
+	return *c.profile, c.jar
+}
+
+func (c *Client) limiter() *Limiter {
+	if c == nil || c.Limiter == nil {
+		return DefaultLimiter
+	}
+	return c.Limiter
+}
+
+func (c *Client) scheme() string {
+	if c == nil || c.Scheme == "" {
+		return "https"
+	}
+	return c.Scheme
+}
+
+func (c *Client) retry() Retry {
+	if c == nil || c.Retry == nil {
+		return DefaultRetry
+	}
+	return *c.Retry
+}
+
+func (c *Client) timeout() time.Duration {
+	if c == nil || c.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return c.Timeout
+}
@@ -0,0 +1,250 @@
+package fetch_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/header"
+)
+
+func TestGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("accept-language"); got != "nl-NL" {
+			t.Errorf("got accept-language %q; want %q", got, "nl-NL")
+		}
+		if got := req.Header.Get("user-agent"); got == "" {
+			t.Error("missing default user-agent header")
+		}
+		if got := req.Header.Get("authority"); got != req.Host {
+			t.Errorf("got authority %q; want %q", got, req.Host)
+		}
+		w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	client := &fetch.Client{
+		Header: http.Header{"Accept-Language": []string{"nl-NL"}},
+	}
+
+	body, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "page" {
+		t.Fatalf("got body %q; want %q", got, "page")
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	agents := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		agents <- req.Header.Get("user-agent")
+		if got := req.Header.Get("accept"); got == "" {
+			t.Error("missing accept header of the profile")
+		}
+		if cookie, err := req.Cookie("i18n-prefs"); err != nil || cookie.Value != "USD" {
+			t.Errorf("got currency cookie %v, err %v; want USD", cookie, err)
+		}
+	}))
+	defer server.Close()
+
+	firefox, ok := header.Lookup("firefox-linux")
+	if !ok {
+		t.Fatal("firefox-linux profile not found")
+	}
+
+	client := &fetch.Client{Limiter: fetch.NewLimiter(0, 1), Profile: firefox}
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := <-agents, firefox.Header.Get("user-agent"); got != want {
+		t.Errorf("got user-agent %q; want %q", got, want)
+	}
+
+	// Without a profile a random one is used on all requests
+	client = &fetch.Client{Limiter: fetch.NewLimiter(0, 1)}
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(server.URL); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, second := <-agents, <-agents
+	if first != second {
+		t.Errorf("got user-agents %q and %q; want the same profile on all requests", first, second)
+	}
+	found := false
+	for _, p := range header.Profiles {
+		found = found || p.Header.Get("user-agent") == first
+	}
+	if !found {
+		t.Errorf("got user-agent %q; want one of the profiles", first)
+	}
+}
+
+func TestGetCurrencyCookie(t *testing.T) {
+	type Test struct {
+		name string
+		// currency already on the cookie jar, if any
+		currency string
+		want     string
+	}
+
+	tests := []Test{
+		{name: "Marketplace", want: "USD"},
+		{name: "OnJar", currency: "EUR", want: "EUR"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			values := make(chan []string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				got := []string{}
+				for _, cookie := range req.Cookies() {
+					if cookie.Name == "i18n-prefs" {
+						got = append(got, cookie.Value)
+					}
+				}
+				values <- got
+			}))
+			defer server.Close()
+
+			jar := fetch.NewCookieJar()
+			if test.currency != "" {
+				u, err := url.Parse(server.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				jar.SetCookies(u, []*http.Cookie{{Name: "i18n-prefs", Value: test.currency, Path: "/"}})
+			}
+
+			client := &fetch.Client{Limiter: fetch.NewLimiter(0, 1), Jar: jar}
+			if _, err := client.Get(server.URL); err != nil {
+				t.Fatal(err)
+			}
+			if got := <-values; !reflect.DeepEqual(got, []string{test.want}) {
+				t.Errorf("got currency cookies %v; want only %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/slow":
+			time.Sleep(time.Second)
+		case "/notfound":
+			http.NotFound(w, req)
+		case "/busy":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("service unavailable"))
+		}
+	}))
+	defer server.Close()
+
+	t.Run("Status", func(t *testing.T) {
+		var client *fetch.Client
+
+		_, err := client.Get(server.URL + "/notfound")
+		if err == nil {
+			t.Fatal("want error on 404")
+		}
+		if !strings.Contains(err.Error(), "unexpected status 404") {
+			t.Fatalf("got error %q; want status on it", err)
+		}
+		if !errors.Is(err, fetch.ErrNotFound) {
+			t.Errorf("got error %v; want %v", err, fetch.ErrNotFound)
+		}
+		if errors.Is(err, fetch.ErrRateLimited) {
+			t.Errorf("got error %v; want it to not be %v", err, fetch.ErrRateLimited)
+		}
+	})
+
+	t.Run("RateLimited", func(t *testing.T) {
+		client := &fetch.Client{Retry: &fetch.Retry{Attempts: 1}}
+
+		_, err := client.Get(server.URL + "/busy")
+		if !errors.Is(err, fetch.ErrRateLimited) {
+			t.Fatalf("got error %v; want %v", err, fetch.ErrRateLimited)
+		}
+
+		var statusErr *fetch.StatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("got error %T; want %T", err, statusErr)
+		}
+		if statusErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("got status %d; want %d", statusErr.StatusCode, http.StatusServiceUnavailable)
+		}
+		if statusErr.Body != "service unavailable" {
+			t.Errorf("got body %q; want %q", statusErr.Body, "service unavailable")
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		client := &fetch.Client{Timeout: 10 * time.Millisecond, Retry: &fetch.Retry{Attempts: 1}}
+
+		_, err := client.Get(server.URL + "/slow")
+		if err == nil {
+			t.Fatal("want timeout error")
+		}
+	})
+}
+
+func TestURL(t *testing.T) {
+	var client *fetch.Client
+
+	if got, want := client.URL("www.amazon.com", "/s"), "https://www.amazon.com/s"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	client = &fetch.Client{Scheme: "http"}
+	if got, want := client.URL("localhost:8080", "/dp/B08KWLMZV4"), "http://localhost:8080/dp/B08KWLMZV4"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	const interval = 50 * time.Millisecond
+
+	limiter := fetch.NewLimiter(interval, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The burst allows the first 2 immediately, the others wait
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Fatalf("4 requests took %v; want at least %v", elapsed, 2*interval)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if err := limiter.Wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v; want %v", err, context.Canceled)
+	}
+
+	if err := fetch.NewLimiter(0, 1).Wait(ctx); err != nil {
+		t.Fatalf("unlimited limiter failed: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Retry is the policy to retry requests that failed because of
+// transient failures: network errors and responses with status 429
+// or 5xx. Captcha challenges are not retried.
+type Retry struct {
+	// Attempts is the max number of attempts of a request, including
+	// the first one. One or less means no retries.
+	Attempts int
+	// MinBackoff is the wait before the first retry, it doubles on
+	// each retry, with a random jitter of up to half of it.
+	MinBackoff time.Duration
+	// MaxBackoff limits the wait between retries, including
+	// the wait asked by the Retry-After of the response.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetry is used by clients without a Retry.
+var DefaultRetry = Retry{
+	Attempts:   3,
+	MinBackoff: time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+// Backoff returns how long to wait before the given retry, the first
+// retry is 1. The Retry-After of the response, if any, is honored
+// instead of the backoff, up to the MaxBackoff, so a server asking
+// for hours can't block the request for that long.
+func (r Retry) Backoff(retry int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		if r.MaxBackoff > 0 && retryAfter > r.MaxBackoff {
+			return r.MaxBackoff
+		}
+		return retryAfter
+	}
+
+	backoff := r.MinBackoff
+	for i := 1; i < retry; i++ {
+		backoff *= 2
+		if r.MaxBackoff > 0 && backoff >= r.MaxBackoff {
+			break
+		}
+	}
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		backoff = r.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// do performs the request until it succeeds, fails with an error
+// that is not transient or the attempts are over.
+func (r Retry) do(ctx context.Context, get func() ([]byte, error)) ([]byte, error) {
+	for retry := 0; ; retry++ {
+		body, err := get()
+		if err == nil || ctx.Err() != nil || !isTransient(err) {
+			return body, err
+		}
+		if retry+1 >= r.Attempts {
+			if retry > 0 {
+				err = fmt.Errorf("%w (after %d attempts)", err, retry+1)
+			}
+			return nil, err
+		}
+
+		var retryAfter time.Duration
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			retryAfter = statusErr.RetryAfter
+		}
+
+		if err := Sleep(ctx, r.Backoff(retry+1, retryAfter)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isTransient reports whether the error may not happen again,
+// like network errors and responses with status 429 or 5xx.
+func isTransient(err error) bool {
+	var captchaErr *CaptchaError
+	if errors.As(err, &captchaErr) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// parseRetryAfter parses the Retry-After header, which
+// can have the seconds to wait or a date.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// Sleep sleeps for the given duration, returning earlier with an error
+// if the context is cancelled.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,193 @@
+package fetch_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/fetch"
+)
+
+func TestRetry(t *testing.T) {
+	type Test struct {
+		name string
+		// fail responds to the request with the given number,
+		// starting at 1, returning true if it failed.
+		fail         func(w http.ResponseWriter, req int32) bool
+		wantRequests int32
+		wantStatus   int
+	}
+
+	tests := []Test{
+		{
+			name: "Unavailable",
+			fail: func(w http.ResponseWriter, req int32) bool {
+				if req < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return true
+				}
+				return false
+			},
+			wantRequests: 3,
+		},
+		{
+			name: "TooManyRequests",
+			fail: func(w http.ResponseWriter, req int32) bool {
+				if req == 1 {
+					w.WriteHeader(http.StatusTooManyRequests)
+					return true
+				}
+				return false
+			},
+			wantRequests: 2,
+		},
+		{
+			name: "NetworkError",
+			fail: func(w http.ResponseWriter, req int32) bool {
+				if req == 1 {
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err == nil {
+						conn.Close()
+					}
+					return true
+				}
+				return false
+			},
+			wantRequests: 2,
+		},
+		{
+			name: "NotFoundIsNotRetried",
+			fail: func(w http.ResponseWriter, req int32) bool {
+				http.NotFound(w, nil)
+				return true
+			},
+			wantRequests: 1,
+			wantStatus:   http.StatusNotFound,
+		},
+		{
+			name: "AttemptsOver",
+			fail: func(w http.ResponseWriter, req int32) bool {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(strings.Repeat("error ", 1000)))
+				return true
+			},
+			wantRequests: 3,
+			wantStatus:   http.StatusInternalServerError,
+		},
+		{
+			name: "CaptchaIsNotRetried",
+			fail: func(w http.ResponseWriter, req int32) bool {
+				w.Write([]byte(captchaPage))
+				return true
+			},
+			wantRequests: 1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if test.fail(w, atomic.AddInt32(&requests, 1)) {
+					return
+				}
+				w.Write([]byte("page"))
+			}))
+			defer server.Close()
+
+			client := &fetch.Client{
+				Limiter: fetch.NewLimiter(0, 1),
+				Retry:   &fetch.Retry{Attempts: 3, MinBackoff: time.Millisecond},
+			}
+
+			_, err := client.Get(server.URL)
+			if got := atomic.LoadInt32(&requests); got != test.wantRequests {
+				t.Errorf("got %d requests; want %d", got, test.wantRequests)
+			}
+
+			if test.wantStatus == 0 {
+				if err != nil && !errors.Is(err, fetch.ErrCaptcha) {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var statusErr *fetch.StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("got error %v; want %T", err, statusErr)
+			}
+			if statusErr.StatusCode != test.wantStatus {
+				t.Errorf("got status %d; want %d", statusErr.StatusCode, test.wantStatus)
+			}
+			if len(statusErr.Body) > fetch.MaxErrorBody+len("...") {
+				t.Errorf("got body of %d bytes; want it truncated to %d", len(statusErr.Body), fetch.MaxErrorBody)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	client := &fetch.Client{
+		Limiter: fetch.NewLimiter(0, 1),
+		Retry:   &fetch.Retry{Attempts: 2, MinBackoff: time.Millisecond},
+	}
+
+	start := time.Now()
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v; want the Retry-After of 1s", elapsed)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	retry := fetch.Retry{Attempts: 10, MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	type Test struct {
+		retry    int
+		min, max time.Duration
+	}
+
+	tests := []Test{
+		{retry: 1, min: 500 * time.Millisecond, max: time.Second},
+		{retry: 2, min: time.Second, max: 2 * time.Second},
+		{retry: 3, min: 2 * time.Second, max: 4 * time.Second},
+		{retry: 5, min: 5 * time.Second, max: 10 * time.Second},
+		{retry: 9, min: 5 * time.Second, max: 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			got := retry.Backoff(test.retry, 0)
+			if got < test.min || got > test.max {
+				t.Fatalf("retry %d got backoff %v; want between %v and %v", test.retry, got, test.min, test.max)
+			}
+		}
+	}
+
+	if got := retry.Backoff(1, 5*time.Second); got != 5*time.Second {
+		t.Errorf("got backoff %v; want the Retry-After %v", got, 5*time.Second)
+	}
+	if got := retry.Backoff(1, time.Hour); got != retry.MaxBackoff {
+		t.Errorf("got backoff %v for a Retry-After of %v; want the max %v", got, time.Hour, retry.MaxBackoff)
+	}
+}
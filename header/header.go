@@ -1,24 +1,165 @@
+// Package header provides browser like headers for the requests to Amazon.
 package header
 
-import "net/http"
+import (
+	"math/rand"
+	"net/http"
+	"net/url"
 
+	"github.com/katcipis/amazoner/marketplace"
+)
+
+// Profile is the fingerprint of a browser, the headers it sends
+// when navigating to a page. The authority and language headers
+// are added according to the marketplace of each request.
+type Profile struct {
+	Name   string
+	Header http.Header
+}
+
+const (
+	chromeAccept  = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"
+	firefoxAccept = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	safariAccept  = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+)
+
+// Profiles are the browser profiles available, DefaultProfile is the first.
+var Profiles = []Profile{
+	{
+		Name: "chrome-linux",
+		Header: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+			"Accept":                    {chromeAccept},
+			"Sec-Ch-Ua":                 {`"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`},
+			"Sec-Ch-Ua-Mobile":          {"?0"},
+			"Sec-Ch-Ua-Platform":        {`"Linux"`},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-User":            {"?1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Rtt":                       {"100"},
+			"Downlink":                  {"10"},
+			"Ect":                       {"4g"},
+		},
+	},
+	{
+		Name: "chrome-windows",
+		Header: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+			"Accept":                    {chromeAccept},
+			"Sec-Ch-Ua":                 {`"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`},
+			"Sec-Ch-Ua-Mobile":          {"?0"},
+			"Sec-Ch-Ua-Platform":        {`"Windows"`},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-User":            {"?1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Rtt":                       {"50"},
+			"Downlink":                  {"10"},
+			"Ect":                       {"4g"},
+		},
+	},
+	{
+		Name: "chrome-mac",
+		Header: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"},
+			"Accept":                    {chromeAccept},
+			"Sec-Ch-Ua":                 {`"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`},
+			"Sec-Ch-Ua-Mobile":          {"?0"},
+			"Sec-Ch-Ua-Platform":        {`"macOS"`},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-User":            {"?1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Rtt":                       {"50"},
+			"Downlink":                  {"10"},
+			"Ect":                       {"4g"},
+		},
+	},
+	{
+		Name: "firefox-windows",
+		Header: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0"},
+			"Accept":                    {firefoxAccept},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-User":            {"?1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Te":                        {"trailers"},
+		},
+	},
+	{
+		Name: "firefox-linux",
+		Header: http.Header{
+			"User-Agent":                {"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"},
+			"Accept":                    {firefoxAccept},
+			"Upgrade-Insecure-Requests": {"1"},
+			"Sec-Fetch-Site":            {"none"},
+			"Sec-Fetch-Mode":            {"navigate"},
+			"Sec-Fetch-User":            {"?1"},
+			"Sec-Fetch-Dest":            {"document"},
+			"Te":                        {"trailers"},
+		},
+	},
+	{
+		Name: "safari-mac",
+		Header: http.Header{
+			"User-Agent":     {"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15"},
+			"Accept":         {safariAccept},
+			"Sec-Fetch-Site": {"none"},
+			"Sec-Fetch-Mode": {"navigate"},
+			"Sec-Fetch-Dest": {"document"},
+		},
+	},
+}
+
+// DefaultProfile is used by Add.
+var DefaultProfile = Profiles[0]
+
+// Add adds browser like headers to the request, with the DefaultProfile.
 func Add(req *http.Request) {
-	req.Header.Add("user-agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/86.0.4240.75 Safari/537.36")
-	// TODO : get authority from the request URL
-	req.Header.Add("authority", "www.amazon.com")
-	req.Header.Add("upgrade-insecure-requests", "1")
-	req.Header.Add("accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9")
-	req.Header.Add("sec-fetch-site", "none")
-	req.Header.Add("sec-fetch-mode", "navigate")
-	req.Header.Add("sec-fetch-user", "?1")
-	req.Header.Add("sec-fetch-dest", "document")
-	req.Header.Add("rtt", "100")
-	req.Header.Add("downlink", "10")
-	req.Header.Add("ect", "4g")
-	req.Header.Add("accept-language", "en-US,en;q=0.9")
-	req.Header.Add("cookie", cookie())
+	DefaultProfile.Add(req)
+}
+
+// Random returns one of the Profiles at random.
+func Random() Profile {
+	return Profiles[rand.Intn(len(Profiles))]
+}
+
+// Lookup returns the profile with the given name.
+func Lookup(name string) (Profile, bool) {
+	for _, p := range Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// Add adds the headers of the profile to the request, with the authority
+// and language of the marketplace of the request URL. The currency is
+// selected by the CurrencyCookie, which must be sent only once, so it is
+// not added, it must be set on the cookie jar or on the request.
+func (p Profile) Add(req *http.Request) {
+	m := marketplace.Of(req.URL.Host)
+
+	for name, values := range p.Header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Add("authority", req.URL.Host)
+	req.Header.Add("accept-language", m.AcceptLanguage)
 }
 
-func cookie() string {
-	return `session-id=138-1486552-1785917; session-id-time=2082787201l; i18n-prefs=USD; sp-cdn="L5Z9:DE"; skin=noskin; ubid-main=133-0026094-6185515; session-token=8s/a5BFqbCmg4b/agSrqTkTYamMzq7VZAdO4muCt7XR3TNZvSYkKJLBczicfYyeydY4xtd+AUJ+wxYuyI+YqmKVxh877jEuRlgBUQPFxl2l8qnvk+VXoLt5yOMyk7kUH8mTkelwiKU1xux3waXmaRi9GzqspHEk9QHSD8Ui/ddfEfSWu7tIx7LVxrflH3sp2vtvJmAnIBAaEwUGI+xEb0EDTDQnrGoDBtADU5sUFgL5/gZyPXOp7E5Z7AyceSlRf; csm-hit=tb:YTW86ZKZQDBF1H4MNT0W+s-YTW86ZKZQDBF1H4MNT0W|1606841550750&t:1606841550750&adb:adblk_no`
+// CurrencyCookie returns the cookie that selects the currency of the
+// marketplace of the given URL, as Amazon sets when it is chosen on
+// the site.
+func CurrencyCookie(u *url.URL) *http.Cookie {
+	m := marketplace.Of(u.Host)
+	return &http.Cookie{Name: "i18n-prefs", Value: m.Currency, Path: "/"}
 }
@@ -0,0 +1,31 @@
+// Package interrupt stops the commands gracefully when the
+// process is interrupted, like with Ctrl+C.
+package interrupt
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Context returns a context that is cancelled when the process is
+// interrupted or terminated. After the first signal, or after cancel
+// is called, signals are no longer caught, so another interrupt
+// kills the process as usual.
+func Context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
+}
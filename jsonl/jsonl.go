@@ -0,0 +1,73 @@
+// Package jsonl loads the JSON Lines files the stores append
+// to, like the price history and the purchase ledger.
+package jsonl
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// MaxLineSize is the max size of a line, longer lines fail the load.
+const MaxLineSize = 1024 * 1024
+
+// Load calls add with each line of the file on the given path, on the
+// order they were written. Lines that add fails to decode are skipped,
+// they are probably partially written, like when the process was killed
+// while writing them. A missing file is loaded as an empty one.
+func Load(path string, add func(line []byte) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, MaxLineSize)
+
+	for scanner.Scan() {
+		// The error only means the line must be skipped
+		_ = add(scanner.Bytes())
+	}
+	return scanner.Err()
+}
+
+// OpenAppend opens the file on the given path to append lines to it,
+// creating it if it doesn't exist. If the last line is partially
+// written it is ended, so the next line is not appended to it and
+// lost with it when the file is loaded.
+func OpenAppend(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := endLastLine(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func endLastLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil && err != io.EOF {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
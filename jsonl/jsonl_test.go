@@ -0,0 +1,114 @@
+package jsonl_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/katcipis/amazoner/jsonl"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "entries.jsonl")
+	lines := []string{
+		`{"id": 1}`,
+		`{"id": 2}`,
+		// Partially written, like when the process was killed
+		`{"id": 3`,
+		`{"id": 4}`,
+	}
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := []int{}
+	err := jsonl.Load(path, func(line []byte) error {
+		var entry struct{ ID int }
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		got = append(got, entry.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{1, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.jsonl")
+	err := jsonl.Load(path, func(line []byte) error {
+		t.Errorf("got line %q on a missing file", line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLineTooLong(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "long.jsonl")
+	line := `{"name": "` + strings.Repeat("a", jsonl.MaxLineSize) + `"}`
+	if err := ioutil.WriteFile(path, []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := jsonl.Load(path, func(line []byte) error { return nil })
+	if err == nil {
+		t.Fatal("want error loading a line longer than the max line size")
+	}
+}
+
+func TestOpenAppend(t *testing.T) {
+	type Test struct {
+		name     string
+		missing  bool
+		contents string
+		want     string
+	}
+
+	tests := []Test{
+		{name: "NewFile", missing: true, want: `{"id": 2}` + "\n"},
+		{name: "EmptyFile", contents: "", want: `{"id": 2}` + "\n"},
+		{name: "CompleteLines", contents: `{"id": 1}` + "\n", want: `{"id": 1}` + "\n" + `{"id": 2}` + "\n"},
+		{name: "PartialLine", contents: `{"id": 1`, want: `{"id": 1` + "\n" + `{"id": 2}` + "\n"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "entries.jsonl")
+			if !test.missing {
+				if err := ioutil.WriteFile(path, []byte(test.contents), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			file, err := jsonl.OpenAppend(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := file.WriteString(`{"id": 2}` + "\n"); err != nil {
+				t.Fatal(err)
+			}
+			if err := file.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got %q; want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package marketplace describes the Amazon marketplaces, like the
+// language and currency of each one.
+package marketplace
+
+import (
+	"net"
+	"strings"
+)
+
+// Marketplace is an Amazon marketplace.
+type Marketplace struct {
+	// Domain is the domain of the marketplace, like www.amazon.de.
+	Domain string
+	// Language is the ISO 639-1 code of the language of the marketplace.
+	Language string
+	// AcceptLanguage is the Accept-Language header used on the marketplace.
+	AcceptLanguage string
+	// Currency is the ISO 4217 code of the currency of the marketplace.
+	Currency string
+}
+
+var marketplaces = []Marketplace{
+	{Domain: "www.amazon.com", Language: "en", AcceptLanguage: "en-US,en;q=0.9", Currency: "USD"},
+	{Domain: "www.amazon.co.uk", Language: "en", AcceptLanguage: "en-GB,en;q=0.9", Currency: "GBP"},
+	{Domain: "www.amazon.de", Language: "de", AcceptLanguage: "de-DE,de;q=0.9,en;q=0.8", Currency: "EUR"},
+	{Domain: "www.amazon.nl", Language: "nl", AcceptLanguage: "nl-NL,nl;q=0.9,en;q=0.8", Currency: "EUR"},
+	{Domain: "www.amazon.fr", Language: "fr", AcceptLanguage: "fr-FR,fr;q=0.9,en;q=0.8", Currency: "EUR"},
+	{Domain: "www.amazon.es", Language: "es", AcceptLanguage: "es-ES,es;q=0.9,en;q=0.8", Currency: "EUR"},
+	{Domain: "www.amazon.it", Language: "it", AcceptLanguage: "it-IT,it;q=0.9,en;q=0.8", Currency: "EUR"},
+	{Domain: "www.amazon.com.br", Language: "pt", AcceptLanguage: "pt-BR,pt;q=0.9,en;q=0.8", Currency: "BRL"},
+	{Domain: "www.amazon.ca", Language: "en", AcceptLanguage: "en-CA,en;q=0.9,fr;q=0.8", Currency: "CAD"},
+	{Domain: "www.amazon.com.mx", Language: "es", AcceptLanguage: "es-MX,es;q=0.9,en;q=0.8", Currency: "MXN"},
+	{Domain: "www.amazon.com.au", Language: "en", AcceptLanguage: "en-AU,en;q=0.9", Currency: "AUD"},
+}
+
+// All returns all the known marketplaces.
+func All() []Marketplace {
+	return append([]Marketplace{}, marketplaces...)
+}
+
+// Lookup returns the marketplace of the given domain, which may be
+// a host with a port or lack the www prefix, like "amazon.de".
+func Lookup(domain string) (Marketplace, bool) {
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	domain = "www." + strings.TrimPrefix(strings.ToLower(domain), "www.")
+
+	for _, m := range marketplaces {
+		if m.Domain == domain {
+			return m, true
+		}
+	}
+	return Marketplace{}, false
+}
+
+// Of returns the marketplace of the given domain, like Lookup,
+// but unknown domains get the settings of www.amazon.com.
+func Of(domain string) Marketplace {
+	if m, ok := Lookup(domain); ok {
+		return m
+	}
+	m := marketplaces[0]
+	m.Domain = domain
+	return m
+}
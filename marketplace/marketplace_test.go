@@ -0,0 +1,43 @@
+package marketplace_test
+
+import (
+	"testing"
+
+	"github.com/katcipis/amazoner/marketplace"
+)
+
+func TestLookup(t *testing.T) {
+	type Test struct {
+		domain       string
+		wantDomain   string
+		wantCurrency string
+		wantOK       bool
+	}
+
+	tests := []Test{
+		{domain: "www.amazon.com", wantDomain: "www.amazon.com", wantCurrency: "USD", wantOK: true},
+		{domain: "amazon.de", wantDomain: "www.amazon.de", wantCurrency: "EUR", wantOK: true},
+		{domain: "www.amazon.nl:443", wantDomain: "www.amazon.nl", wantCurrency: "EUR", wantOK: true},
+		{domain: "WWW.Amazon.co.uk", wantDomain: "www.amazon.co.uk", wantCurrency: "GBP", wantOK: true},
+		{domain: "www.amazon.com.br", wantDomain: "www.amazon.com.br", wantCurrency: "BRL", wantOK: true},
+		{domain: "127.0.0.1:8080"},
+	}
+
+	for _, test := range tests {
+		m, ok := marketplace.Lookup(test.domain)
+		if ok != test.wantOK {
+			t.Errorf("%q: got ok %t; want %t", test.domain, ok, test.wantOK)
+			continue
+		}
+		if m.Domain != test.wantDomain || m.Currency != test.wantCurrency {
+			t.Errorf("%q: got marketplace %+v; want domain %q currency %q", test.domain, m, test.wantDomain, test.wantCurrency)
+		}
+	}
+}
+
+func TestOfUnknown(t *testing.T) {
+	m := marketplace.Of("127.0.0.1:8080")
+	if m.Domain != "127.0.0.1:8080" || m.Currency != "USD" || m.AcceptLanguage == "" {
+		t.Errorf("got marketplace %+v; want www.amazon.com settings on the unknown domain", m)
+	}
+}
@@ -0,0 +1,370 @@
+// Package money provides an exact representation for prices, avoiding
+// the rounding problems of representing money as floating point numbers.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Money represents an amount of money in the minor units of its currency
+// (eg. cents for USD/EUR), so all operations are exact.
+type Money struct {
+	// Amount is the value in minor units of the currency.
+	Amount int64
+	// Currency is the ISO 4217 code of the currency.
+	// It may be empty if the currency is unknown, in which case
+	// the money is considered compatible with any currency. Money
+	// without currency has two decimal digits, it is rescaled to the
+	// decimals of the currency it is compared or added to.
+	Currency string
+}
+
+type Error string
+
+const (
+	ErrCurrencyMismatch Error = "currency mismatch"
+	ErrInvalid          Error = "invalid money"
+)
+
+// currencySymbols maps currency symbols to ISO codes.
+// Longer symbols must come first since they may contain shorter ones.
+var currencySymbols = []struct {
+	symbol string
+	code   string
+}{
+	{"CDN$", "CAD"},
+	{"US$", "USD"},
+	{"AU$", "AUD"},
+	{"MX$", "MXN"},
+	{"R$", "BRL"},
+	{"C$", "CAD"},
+	{"A$", "AUD"},
+	{"S$", "SGD"},
+	{"zł", "PLN"},
+	{"₹", "INR"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"¥", "JPY"},
+	{"$", "USD"},
+}
+
+// dollarCurrencies are the currencies whose symbol is also
+// the $ symbol, which alone is ambiguous.
+var dollarCurrencies = map[string]struct{}{
+	"AUD": {}, "CAD": {}, "MXN": {}, "SGD": {}, "USD": {},
+}
+
+var currencyCodes = map[string]struct{}{
+	"AUD": {}, "BRL": {}, "CAD": {}, "CHF": {}, "EUR": {}, "GBP": {},
+	"INR": {}, "JPY": {}, "MXN": {}, "PLN": {}, "SEK": {}, "SGD": {},
+	"TRY": {}, "USD": {},
+}
+
+// zeroDecimalCurrencies are the currencies without minor units.
+var zeroDecimalCurrencies = map[string]struct{}{
+	"JPY": {},
+}
+
+var (
+	codeRe   = regexp.MustCompile(`\b[A-Z]{3}\b`)
+	numberRe = regexp.MustCompile(`(?:\d|[.,]\d)(?:[\d.,' ]*\d)?`)
+)
+
+// Parse parses a price as presented on Amazon pages, like "$1,234.56",
+// "1.234,56 €", "EUR 12,99" or "R$ 1.234,56".
+//
+// The decimal separator is detected from the text itself, so both
+// the english and the european conventions are supported.
+// A separator followed by exactly three digits is always considered
+// a thousands separator. If the text has no currency symbol or code
+// the returned money has no currency.
+//
+// Only the first amount on the text is parsed, so text with
+// repeated prices (like a price range) is accepted. Amounts next
+// to the currency symbol are preferred, so text like
+// "New & Used (12) from $612.50" is parsed as $612.50.
+//
+// The $ symbol alone is parsed as USD, use ParseIn to parse
+// prices of marketplaces with other dollar currencies.
+func Parse(s string) (Money, error) {
+	return ParseIn(s, "")
+}
+
+// ParseIn is like Parse, but the text is from a page whose prices are
+// on the given local currency, like CAD for www.amazon.ca. The $ symbol
+// alone has the local currency if it is a dollar, like CAD or AUD.
+func ParseIn(s, local string) (Money, error) {
+	normalized := strings.NewReplacer(
+		"\u00a0", " ", // no-break space
+		"\u202f", " ", // narrow no-break space
+	).Replace(s)
+
+	currency, symbol := parseCurrency(normalized)
+	if _, ok := dollarCurrencies[local]; ok && symbol == "$" {
+		currency = local
+	}
+
+	number := findAmount(normalized, symbol)
+	if number == "" {
+		return Money{}, fmt.Errorf("%w : can't find amount on %q", ErrInvalid, s)
+	}
+
+	amount, err := parseAmount(number, Decimals(currency))
+	if err != nil {
+		return Money{}, fmt.Errorf("%w : can't parse %q : %v", ErrInvalid, s, err)
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// Decimals returns how many decimal digits the minor units of the
+// given currency have.
+func Decimals(currency string) int {
+	if _, ok := zeroDecimalCurrencies[currency]; ok {
+		return 0
+	}
+	return 2
+}
+
+// Cmp compares two amounts of money, returning -1, 0 or 1 if m is
+// respectively less than, equal or greater than o.
+// It fails if both have a currency and they differ.
+func (m Money) Cmp(o Money) (int, error) {
+	if !m.compatible(o) {
+		return 0, fmt.Errorf("%w : can't compare %v with %v", ErrCurrencyMismatch, m, o)
+	}
+
+	// Compared on the same scale, since money without
+	// currency may have other decimals than o
+	a := m.Amount * pow10(Decimals(o.Currency))
+	b := o.Amount * pow10(Decimals(m.Currency))
+	switch {
+	case a < b:
+		return -1, nil
+	case a > b:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Add returns the sum of the moneys, failing if their currencies differ.
+// The sum has the currency of the operand that has one.
+func (m Money) Add(o Money) (Money, error) {
+	if !m.compatible(o) {
+		return Money{}, fmt.Errorf("%w : can't add %v to %v", ErrCurrencyMismatch, o, m)
+	}
+	currency := m.Currency
+	if currency == "" {
+		currency = o.Currency
+	}
+	return Money{Amount: m.In(currency).Amount + o.In(currency).Amount, Currency: currency}, nil
+}
+
+// IsZero returns true if the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Floor returns the amount in major units rounded down.
+func (m Money) Floor() int64 {
+	scale := pow10(Decimals(m.Currency))
+	v := m.Amount / scale
+	if m.Amount < 0 && m.Amount%scale != 0 {
+		v--
+	}
+	return v
+}
+
+// Ceil returns the amount in major units rounded up.
+func (m Money) Ceil() int64 {
+	scale := pow10(Decimals(m.Currency))
+	v := m.Amount / scale
+	if m.Amount > 0 && m.Amount%scale != 0 {
+		v++
+	}
+	return v
+}
+
+// String formats the money as an exact decimal followed by its
+// currency code, like "1234.56 USD".
+func (m Money) String() string {
+	dec := Decimals(m.Currency)
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	scale := pow10(dec)
+	s := sign + strconv.FormatInt(amount/scale, 10)
+	if dec > 0 {
+		s += fmt.Sprintf(".%0*d", dec, amount%scale)
+	}
+	if m.Currency != "" {
+		s += " " + m.Currency
+	}
+	return s
+}
+
+// Set parses the given string into the money, it makes
+// *Money a flag.Value.
+func (m *Money) Set(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// In returns money without currency on the given currency, rescaling
+// its amount to the decimals of the currency. Money that already has
+// a currency is returned as it is, use Rates.Convert to convert it.
+func (m Money) In(currency string) Money {
+	if m.Currency != "" || currency == "" {
+		return m
+	}
+
+	from, to := Decimals(""), Decimals(currency)
+	amount := m.Amount
+	if to > from {
+		amount *= pow10(to - from)
+	}
+	if from > to {
+		// Rounded once, rounding each decimal would round 1.49 to 2
+		amount = int64(math.Round(float64(amount) / float64(pow10(from-to))))
+	}
+	return Money{Amount: amount, Currency: currency}
+}
+
+func (m Money) compatible(o Money) bool {
+	return m.Currency == "" || o.Currency == "" || m.Currency == o.Currency
+}
+
+// parseCurrency returns the currency code found on s and
+// the symbol/code used to represent it on s.
+func parseCurrency(s string) (string, string) {
+	for _, code := range codeRe.FindAllString(s, -1) {
+		if _, ok := currencyCodes[code]; ok {
+			return code, code
+		}
+	}
+	for _, cs := range currencySymbols {
+		if strings.Contains(s, cs.symbol) {
+			return cs.code, cs.symbol
+		}
+	}
+	return "", ""
+}
+
+// findAmount finds the first number adjacent to the currency symbol,
+// defaulting to the first number on s.
+func findAmount(s, symbol string) string {
+	indexes := numberRe.FindAllStringIndex(s, -1)
+	if len(indexes) == 0 {
+		return ""
+	}
+	if symbol != "" {
+		for _, index := range indexes {
+			before := strings.TrimRight(s[:index[0]], " ")
+			after := strings.TrimLeft(s[index[1]:], " ")
+			if strings.HasSuffix(before, symbol) || strings.HasPrefix(after, symbol) {
+				return s[index[0]:index[1]]
+			}
+		}
+	}
+	first := indexes[0]
+	return s[first[0]:first[1]]
+}
+
+func parseAmount(number string, decimals int) (int64, error) {
+	number = strings.NewReplacer(" ", "", "'", "").Replace(number)
+
+	intPart, fracPart := number, ""
+	if strings.IndexAny(number, ".,") == 0 {
+		// A leading separator is always decimal, like on $.99
+		intPart, fracPart = "0", number[1:]
+		if strings.ContainsAny(fracPart, ".,") {
+			return 0, fmt.Errorf("malformed amount %q", number)
+		}
+	} else if i := strings.LastIndexAny(number, ".,"); i >= 0 {
+		sep, other := number[i:i+1], "."
+		if sep == "." {
+			other = ","
+		}
+		single := strings.Count(number, sep) == 1
+		// With both separators the last one is always decimal, like on 1.234,56
+		mixed := strings.Contains(number[:i], other)
+		switch {
+		case mixed && !single:
+			return 0, fmt.Errorf("malformed amount %q", number)
+		case mixed || single && len(number[i+1:]) != 3:
+			intPart, fracPart = number[:i], number[i+1:]
+		}
+	}
+
+	if err := checkGroups(intPart); err != nil {
+		return 0, err
+	}
+	intPart = strings.NewReplacer(".", "", ",", "").Replace(intPart)
+
+	if len(fracPart) > decimals {
+		return 0, fmt.Errorf("too many decimal digits %q", fracPart)
+	}
+	fracPart += strings.Repeat("0", decimals-len(fracPart))
+
+	units, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if units > math.MaxInt64/pow10(decimals) {
+		return 0, errors.New("amount overflows")
+	}
+
+	var minor int64
+	if fracPart != "" {
+		minor, err = strconv.ParseInt(fracPart, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return units*pow10(decimals) + minor, nil
+}
+
+// checkGroups checks that all thousands separators are followed
+// by exactly three digits, rejecting malformed amounts.
+func checkGroups(intPart string) error {
+	groups := strings.FieldsFunc(intPart, func(r rune) bool {
+		return r == '.' || r == ','
+	})
+	for i, group := range groups {
+		if i == 0 {
+			if group == "" {
+				return fmt.Errorf("malformed amount %q", intPart)
+			}
+			continue
+		}
+		if len(group) != 3 {
+			return fmt.Errorf("malformed thousands group %q on %q", group, intPart)
+		}
+	}
+	return nil
+}
+
+func pow10(n int) int64 {
+	v := int64(1)
+	for i := 0; i < n; i++ {
+		v *= 10
+	}
+	return v
+}
@@ -0,0 +1,234 @@
+package money_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/katcipis/amazoner/money"
+)
+
+func TestParse(t *testing.T) {
+	type Test struct {
+		input string
+		want  money.Money
+	}
+
+	tests := []Test{
+		{input: "$1,234.56", want: money.Money{Amount: 123456, Currency: "USD"}},
+		{input: "1.234,56 €", want: money.Money{Amount: 123456, Currency: "EUR"}},
+		{input: "1 234,56 €", want: money.Money{Amount: 123456, Currency: "EUR"}},
+		{input: "EUR 12,99", want: money.Money{Amount: 1299, Currency: "EUR"}},
+		{input: "R$ 1.234,5", want: money.Money{Amount: 123450, Currency: "BRL"}},
+		{input: "£939.99", want: money.Money{Amount: 93999, Currency: "GBP"}},
+		{input: "CDN$ 99.00", want: money.Money{Amount: 9900, Currency: "CAD"}},
+		{input: "¥12,345", want: money.Money{Amount: 12345, Currency: "JPY"}},
+		{input: "$1,000", want: money.Money{Amount: 100000, Currency: "USD"}},
+		{input: "1.000 €", want: money.Money{Amount: 100000, Currency: "EUR"}},
+		{input: "$1,234,567.89", want: money.Money{Amount: 123456789, Currency: "USD"}},
+		{input: "\n  $939.99$939.99 ", want: money.Money{Amount: 93999, Currency: "USD"}},
+		{input: "$10.99 - $12.99", want: money.Money{Amount: 1099, Currency: "USD"}},
+		{input: "1000.99", want: money.Money{Amount: 100099}},
+		{input: "1000", want: money.Money{Amount: 100000}},
+		{input: "1234.56 USD", want: money.Money{Amount: 123456, Currency: "USD"}},
+		{input: "New & Used (12) from $612.50", want: money.Money{Amount: 61250, Currency: "USD"}},
+		{input: "Neu (3) ab EUR 540,00", want: money.Money{Amount: 54000, Currency: "EUR"}},
+		{input: "$.99", want: money.Money{Amount: 99, Currency: "USD"}},
+		{input: ",99 €", want: money.Money{Amount: 99, Currency: "EUR"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := money.Parse(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("got %+v; want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseIn(t *testing.T) {
+	type Test struct {
+		input    string
+		currency string
+		want     money.Money
+	}
+
+	tests := []Test{
+		{input: "$99.00", currency: "CAD", want: money.Money{Amount: 9900, Currency: "CAD"}},
+		{input: "$1,299.00", currency: "AUD", want: money.Money{Amount: 129900, Currency: "AUD"}},
+		{input: "$.99", currency: "MXN", want: money.Money{Amount: 99, Currency: "MXN"}},
+		{input: "$99.00", currency: "EUR", want: money.Money{Amount: 9900, Currency: "USD"}},
+		{input: "US$99.00", currency: "CAD", want: money.Money{Amount: 9900, Currency: "USD"}},
+		{input: "CDN$ 99.00", currency: "USD", want: money.Money{Amount: 9900, Currency: "CAD"}},
+		{input: "12,99 €", currency: "GBP", want: money.Money{Amount: 1299, Currency: "EUR"}},
+		{input: "99.50", currency: "CAD", want: money.Money{Amount: 9950}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input+" "+test.currency, func(t *testing.T) {
+			got, err := money.ParseIn(test.input, test.currency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("got %+v; want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseFailure(t *testing.T) {
+	inputs := []string{
+		"",
+		"Currently unavailable.",
+		"$1,23,4.56",
+		"1.234.56",
+		"$1.2345",
+		"$.9.9",
+		"$12,345.678",
+		"1,234.567.890",
+		"1.234,567,89",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			got, err := money.Parse(input)
+			if !errors.Is(err, money.ErrInvalid) {
+				t.Fatalf("got %+v, err %v; want %v", got, err, money.ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestCmp(t *testing.T) {
+	type Test struct {
+		name string
+		a    money.Money
+		b    money.Money
+		want int
+	}
+
+	tests := []Test{
+		{
+			name: "cents above limit",
+			a:    money.Money{Amount: 100099, Currency: "USD"},
+			b:    money.Money{Amount: 100000},
+			want: 1,
+		},
+		{
+			name: "equal",
+			a:    money.Money{Amount: 100000, Currency: "USD"},
+			b:    money.Money{Amount: 100000, Currency: "USD"},
+			want: 0,
+		},
+		{
+			name: "below",
+			a:    money.Money{Amount: 99999, Currency: "EUR"},
+			b:    money.Money{Amount: 100000, Currency: "EUR"},
+			want: -1,
+		},
+		{
+			name: "yen equal to limit without currency",
+			a:    money.Money{Amount: 1000, Currency: "JPY"},
+			b:    money.Money{Amount: 100000},
+			want: 0,
+		},
+		{
+			name: "yen above limit without currency",
+			a:    money.Money{Amount: 1001, Currency: "JPY"},
+			b:    money.Money{Amount: 100000},
+			want: 1,
+		},
+		{
+			name: "limit without currency below yen",
+			a:    money.Money{Amount: 99999},
+			b:    money.Money{Amount: 1000, Currency: "JPY"},
+			want: -1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.a.Cmp(test.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Fatalf("%v.Cmp(%v) = %d; want %d", test.a, test.b, got, test.want)
+			}
+		})
+	}
+
+	t.Run("currency mismatch", func(t *testing.T) {
+		a := money.Money{Amount: 100, Currency: "USD"}
+		b := money.Money{Amount: 100, Currency: "EUR"}
+		_, err := a.Cmp(b)
+		if !errors.Is(err, money.ErrCurrencyMismatch) {
+			t.Fatalf("got err %v; want %v", err, money.ErrCurrencyMismatch)
+		}
+	})
+}
+
+func TestAdd(t *testing.T) {
+	shipping := money.Money{Amount: 1499, Currency: "USD"}
+
+	got, err := money.Money{Amount: 35999}.Add(shipping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (money.Money{Amount: 37498, Currency: "USD"}); got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+
+	got, err = money.Money{Amount: 1000, Currency: "JPY"}.Add(money.Money{Amount: 25000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (money.Money{Amount: 1250, Currency: "JPY"}); got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+
+	_, err = shipping.Add(money.Money{Amount: 100, Currency: "EUR"})
+	if !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Fatalf("got err %v; want %v", err, money.ErrCurrencyMismatch)
+	}
+}
+
+func TestRounding(t *testing.T) {
+	m := money.Money{Amount: 100099, Currency: "USD"}
+	if got := m.Floor(); got != 1000 {
+		t.Errorf("%v.Floor() = %d; want 1000", m, got)
+	}
+	if got := m.Ceil(); got != 1001 {
+		t.Errorf("%v.Ceil() = %d; want 1001", m, got)
+	}
+	if got := m.String(); got != "1000.99 USD" {
+		t.Errorf("%v.String() = %q; want %q", m, got, "1000.99 USD")
+	}
+}
+
+func TestIn(t *testing.T) {
+	type Test struct {
+		m        money.Money
+		currency string
+		want     money.Money
+	}
+
+	tests := []Test{
+		{m: money.Money{Amount: 1299}, currency: "EUR", want: money.Money{Amount: 1299, Currency: "EUR"}},
+		{m: money.Money{Amount: 100000}, currency: "JPY", want: money.Money{Amount: 1000, Currency: "JPY"}},
+		{m: money.Money{Amount: 149}, currency: "JPY", want: money.Money{Amount: 1, Currency: "JPY"}},
+		{m: money.Money{Amount: 150}, currency: "JPY", want: money.Money{Amount: 2, Currency: "JPY"}},
+		{m: money.Money{Amount: 1299, Currency: "USD"}, currency: "EUR", want: money.Money{Amount: 1299, Currency: "USD"}},
+		{m: money.Money{Amount: 1299}, currency: "", want: money.Money{Amount: 1299}},
+	}
+
+	for _, test := range tests {
+		if got := test.m.In(test.currency); got != test.want {
+			t.Errorf("%+v.In(%q) = %+v; want %+v", test.m, test.currency, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Rates is an exchange rate table, with how much of the Base
+// currency one unit of each of the other currencies is worth,
+// like {"base": "EUR", "rates": {"USD": 0.82, "GBP": 1.12}}.
+type Rates struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+const ErrUnknownRate Error = "unknown exchange rate"
+
+// LoadRates loads the rates from the JSON file on the given path.
+func LoadRates(path string) (Rates, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Rates{}, err
+	}
+	defer file.Close()
+
+	return ParseRates(file)
+}
+
+// ParseRates parses the rates from JSON.
+func ParseRates(r io.Reader) (Rates, error) {
+	var rates Rates
+	if err := json.NewDecoder(r).Decode(&rates); err != nil {
+		return Rates{}, fmt.Errorf("parsing exchange rates : %v", err)
+	}
+	if rates.Base == "" {
+		return Rates{}, fmt.Errorf("exchange rates have no base currency")
+	}
+	for currency, rate := range rates.Rates {
+		if rate <= 0 {
+			return Rates{}, fmt.Errorf("exchange rate of %s must be positive, got %v", currency, rate)
+		}
+	}
+	return rates, nil
+}
+
+// Convert converts the money to the given currency, rounding it to the
+// nearest minor unit. Money without a currency can't be converted, since
+// its currency is unknown, use Money.In to give it a currency first.
+func (r Rates) Convert(m Money, currency string) (Money, error) {
+	if m.Currency == "" {
+		return Money{}, fmt.Errorf("%w : from money without currency %v to %s", ErrUnknownRate, m, currency)
+	}
+	if m.Currency == currency {
+		return m, nil
+	}
+
+	from, err := r.rate(m.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	to, err := r.rate(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	major := float64(m.Amount) / float64(pow10(Decimals(m.Currency)))
+	converted := major * from / to * float64(pow10(Decimals(currency)))
+	return Money{Amount: int64(math.Round(converted)), Currency: currency}, nil
+}
+
+func (r Rates) rate(currency string) (float64, error) {
+	if currency == r.Base {
+		return 1, nil
+	}
+	rate, ok := r.Rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("%w : from %s to %s", ErrUnknownRate, currency, r.Base)
+	}
+	return rate, nil
+}
@@ -0,0 +1,97 @@
+package money_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/katcipis/amazoner/money"
+)
+
+func TestRatesConvert(t *testing.T) {
+	rates, err := money.ParseRates(strings.NewReader(`{
+		"base": "EUR",
+		"rates": {"USD": 0.82, "GBP": 1.12, "BRL": 0.16, "JPY": 0.0079}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type Test struct {
+		name     string
+		m        money.Money
+		currency string
+		want     money.Money
+	}
+
+	tests := []Test{
+		{
+			name:     "ToBase",
+			m:        money.Money{Amount: 59999, Currency: "USD"},
+			currency: "EUR",
+			want:     money.Money{Amount: 49199, Currency: "EUR"},
+		},
+		{
+			name:     "FromBase",
+			m:        money.Money{Amount: 49199, Currency: "EUR"},
+			currency: "USD",
+			want:     money.Money{Amount: 59999, Currency: "USD"},
+		},
+		{
+			name:     "BetweenOthers",
+			m:        money.Money{Amount: 100000, Currency: "GBP"},
+			currency: "BRL",
+			want:     money.Money{Amount: 700000, Currency: "BRL"},
+		},
+		{
+			name:     "ZeroDecimals",
+			m:        money.Money{Amount: 100000, Currency: "JPY"},
+			currency: "EUR",
+			want:     money.Money{Amount: 79000, Currency: "EUR"},
+		},
+		{
+			name:     "SameCurrency",
+			m:        money.Money{Amount: 1234, Currency: "CHF"},
+			currency: "CHF",
+			want:     money.Money{Amount: 1234, Currency: "CHF"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			got, err := rates.Convert(test.m, test.currency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+		})
+	}
+
+	_, err = rates.Convert(money.Money{Amount: 100, Currency: "CHF"}, "EUR")
+	if !errors.Is(err, money.ErrUnknownRate) {
+		t.Errorf("got err %v; want %v", err, money.ErrUnknownRate)
+	}
+
+	_, err = rates.Convert(money.Money{Amount: 1234}, "USD")
+	if !errors.Is(err, money.ErrUnknownRate) {
+		t.Errorf("converting money without currency: got err %v; want %v", err, money.ErrUnknownRate)
+	}
+}
+
+func TestParseRatesFailures(t *testing.T) {
+	tests := []string{
+		`{"base": "EUR", "rates": `,
+		`{"rates": {"USD": 0.82}}`,
+		`{"base": "EUR", "rates": {"USD": 0}}`,
+		`{"base": "EUR", "rates": {"USD": -1}}`,
+	}
+
+	for _, test := range tests {
+		if _, err := money.ParseRates(strings.NewReader(test)); err == nil {
+			t.Errorf("want error parsing rates %s", test)
+		}
+	}
+}
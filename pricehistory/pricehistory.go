@@ -0,0 +1,319 @@
+// Package pricehistory records the prices of products over time,
+// allowing to find out if a price is a good deal or not.
+package pricehistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/katcipis/amazoner/jsonl"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+)
+
+// Key identifies a product on a marketplace, the same ASIN
+// may have different prices on different domains.
+type Key struct {
+	Domain string `json:"domain"`
+	ASIN   string `json:"asin"`
+}
+
+// Observation is the price of a product observed at some time.
+type Observation struct {
+	Key
+	Time  time.Time   `json:"time"`
+	Name  string      `json:"name"`
+	Price money.Money `json:"price"`
+	// LandedPrice is the price plus shipping and import fees.
+	LandedPrice money.Money `json:"landed_price"`
+}
+
+// Stats are statistics of the prices of a product over a period.
+type Stats struct {
+	Min   money.Money
+	Max   money.Money
+	Avg   money.Money
+	Count int
+	// First and Last are the times of the first and last
+	// observations of the period.
+	First time.Time
+	Last  time.Time
+}
+
+// Deal is a price of a product below its average price.
+type Deal struct {
+	// Current is the latest observation of the product.
+	Current Observation
+	// Average is the average price over the period before
+	// the latest observation, the latest one included.
+	Average money.Money
+	// Percent is how much the current price is below the average.
+	Percent float64
+}
+
+type Error string
+
+const (
+	ErrNoObservations Error = "no observations"
+	ErrNoASIN         Error = "product has no ASIN"
+)
+
+// Store stores the observations on a file, one JSON line
+// for each observation, so observations are only appended.
+// It is safe for concurrent use.
+type Store struct {
+	mu           sync.Mutex
+	file         *os.File
+	observations map[Key][]Observation
+}
+
+// Open opens the store on the given path, creating the file
+// if it doesn't exist. The store must be closed after use.
+func Open(path string) (*Store, error) {
+	observations, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := jsonl.OpenAppend(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		file:         file,
+		observations: observations,
+	}, nil
+}
+
+// KeyOf returns the key of the given product.
+func KeyOf(prod product.Product) (Key, error) {
+	if prod.ASIN == "" {
+		return Key{}, fmt.Errorf("%w : %q", ErrNoASIN, prod.URL)
+	}
+
+	link := prod.CanonicalURL
+	if link == "" {
+		link = prod.URL
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{Domain: u.Host, ASIN: prod.ASIN}, nil
+}
+
+// Record records the price of the product observed at the given time.
+func (s *Store) Record(prod product.Product, at time.Time) error {
+	key, err := KeyOf(prod)
+	if err != nil {
+		return err
+	}
+
+	landedPrice, err := prod.LandedPrice()
+	if err != nil {
+		return err
+	}
+
+	return s.Add(Observation{
+		Key:         key,
+		Time:        at,
+		Name:        prod.Name,
+		Price:       prod.Price,
+		LandedPrice: landedPrice,
+	})
+}
+
+// Add adds the observation to the store, persisting it on the file.
+func (s *Store) Add(obs Observation) error {
+	line, err := json.Marshal(obs)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing price history : %v", err)
+	}
+
+	s.observations[obs.Key] = insert(s.observations[obs.Key], obs)
+	return nil
+}
+
+// Keys returns the keys of all products with observations.
+func (s *Store) Keys() []Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]Key, 0, len(s.observations))
+	for key := range s.observations {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Domain != keys[j].Domain {
+			return keys[i].Domain < keys[j].Domain
+		}
+		return keys[i].ASIN < keys[j].ASIN
+	})
+	return keys
+}
+
+// Observations returns the observations of the product between
+// since and until (both included), ordered by time.
+func (s *Store) Observations(key Key, since, until time.Time) []Observation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := []Observation{}
+	for _, obs := range s.observations[key] {
+		if obs.Time.Before(since) || obs.Time.After(until) {
+			continue
+		}
+		res = append(res, obs)
+	}
+	return res
+}
+
+// Latest returns the latest observation of the product.
+func (s *Store) Latest(key Key) (Observation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	observations := s.observations[key]
+	if len(observations) == 0 {
+		return Observation{}, false
+	}
+	return observations[len(observations)-1], true
+}
+
+// Stats returns the statistics of the prices of the
+// product between since and until (both included).
+func (s *Store) Stats(key Key, since, until time.Time) (Stats, error) {
+	observations := s.Observations(key, since, until)
+	if len(observations) == 0 {
+		return Stats{}, fmt.Errorf("%w for %v between %v and %v", ErrNoObservations, key, since, until)
+	}
+	return stats(observations)
+}
+
+// CheckDeal checks if the latest price of the product is at least
+// minPercent below its average price over the window before it.
+func (s *Store) CheckDeal(key Key, window time.Duration, minPercent float64) (Deal, bool, error) {
+	latest, ok := s.Latest(key)
+	if !ok {
+		return Deal{}, false, fmt.Errorf("%w for %v", ErrNoObservations, key)
+	}
+
+	st, err := s.Stats(key, latest.Time.Add(-window), latest.Time)
+	if err != nil {
+		return Deal{}, false, err
+	}
+	if st.Avg.IsZero() {
+		return Deal{}, false, nil
+	}
+
+	deal := Deal{
+		Current: latest,
+		Average: st.Avg,
+		Percent: float64(st.Avg.Amount-latest.Price.Amount) / float64(st.Avg.Amount) * 100,
+	}
+	return deal, deal.Percent >= minPercent, nil
+}
+
+// Deals returns the deals of all the products on the store,
+// see CheckDeal. Products whose deal can't be checked are ignored.
+func (s *Store) Deals(window time.Duration, minPercent float64) []Deal {
+	deals := []Deal{}
+	for _, key := range s.Keys() {
+		deal, ok, err := s.CheckDeal(key, window, minPercent)
+		if err != nil || !ok {
+			continue
+		}
+		deals = append(deals, deal)
+	}
+	return deals
+}
+
+// Close closes the store file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func stats(observations []Observation) (Stats, error) {
+	st := Stats{
+		Min:   observations[0].Price,
+		Max:   observations[0].Price,
+		Count: len(observations),
+		First: observations[0].Time,
+		Last:  observations[len(observations)-1].Time,
+	}
+
+	sum := money.Money{}
+	for _, obs := range observations {
+		var err error
+		if sum, err = sum.Add(obs.Price); err != nil {
+			return Stats{}, err
+		}
+		if cmp, _ := obs.Price.Cmp(st.Min); cmp < 0 {
+			st.Min = obs.Price
+		}
+		if cmp, _ := obs.Price.Cmp(st.Max); cmp > 0 {
+			st.Max = obs.Price
+		}
+	}
+
+	st.Avg = money.Money{
+		Amount:   roundDiv(sum.Amount, int64(len(observations))),
+		Currency: sum.Currency,
+	}
+	return st, nil
+}
+
+// insert inserts the observation keeping the observations ordered by time.
+func insert(observations []Observation, obs Observation) []Observation {
+	i := sort.Search(len(observations), func(i int) bool {
+		return observations[i].Time.After(obs.Time)
+	})
+	observations = append(observations, Observation{})
+	copy(observations[i+1:], observations[i:])
+	observations[i] = obs
+	return observations
+}
+
+func load(path string) (map[Key][]Observation, error) {
+	observations := map[Key][]Observation{}
+
+	err := jsonl.Load(path, func(line []byte) error {
+		var obs Observation
+		if err := json.Unmarshal(line, &obs); err != nil {
+			return err
+		}
+		observations[obs.Key] = insert(observations[obs.Key], obs)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading price history %q : %v", path, err)
+	}
+	return observations, nil
+}
+
+func roundDiv(a, b int64) int64 {
+	if a < 0 {
+		return -roundDiv(-a, b)
+	}
+	return (a + b/2) / b
+}
+
+func (e Error) Error() string {
+	return string(e)
+}
@@ -0,0 +1,137 @@
+package pricehistory_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/pricehistory"
+	"github.com/katcipis/amazoner/product"
+)
+
+var start = time.Date(2020, time.December, 1, 12, 0, 0, 0, time.UTC)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.jsonl")
+
+	store, err := pricehistory.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prices := []int64{60000, 58000, 62000, 60000, 49000}
+	for i, price := range prices {
+		if err := store.Record(gpu(price), start.Add(time.Duration(i)*24*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other := gpu(1000)
+	other.CanonicalURL = "https://www.amazon.de/dp/B08KWLMZV4"
+	other.Price.Currency = "EUR"
+	if err := store.Record(other, start); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Partially written observation, must be ignored
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"domain":"www.amazon.com","asin":"B08K`)
+	file.Close()
+
+	store, err = pricehistory.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	key := pricehistory.Key{Domain: "www.amazon.com", ASIN: "B08KWLMZV4"}
+
+	if got := len(store.Keys()); got != 2 {
+		t.Fatalf("got %d keys; want 2", got)
+	}
+
+	st, err := store.Stats(key, start, start.Add(3*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pricehistory.Stats{
+		Min:   usd(58000),
+		Max:   usd(62000),
+		Avg:   usd(60000),
+		Count: 4,
+		First: start,
+		Last:  start.Add(3 * 24 * time.Hour),
+	}
+	if !st.First.Equal(want.First) || !st.Last.Equal(want.Last) {
+		t.Errorf("got period %v - %v; want %v - %v", st.First, st.Last, want.First, want.Last)
+	}
+	st.First, st.Last, want.First, want.Last = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+	if st != want {
+		t.Errorf("got stats %+v; want %+v", st, want)
+	}
+
+	deal, ok, err := store.CheckDeal(key, 30*24*time.Hour, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("want deal, got %+v", deal)
+	}
+	if deal.Current.Price != usd(49000) || deal.Average != usd(57800) {
+		t.Errorf("got deal %+v; want current %v and average %v", deal, usd(49000), usd(57800))
+	}
+	if deal.Percent < 15.2 || deal.Percent > 15.3 {
+		t.Errorf("got %.2f%% below average; want 15.22%%", deal.Percent)
+	}
+
+	if _, ok, _ := store.CheckDeal(key, 30*24*time.Hour, 20); ok {
+		t.Error("want no deal with 20% threshold")
+	}
+	if deals := store.Deals(30*24*time.Hour, 10); len(deals) != 1 {
+		t.Errorf("got deals %+v; want 1", deals)
+	}
+
+	_, err = store.Stats(key, start.Add(-48*time.Hour), start.Add(-24*time.Hour))
+	if !errors.Is(err, pricehistory.ErrNoObservations) {
+		t.Errorf("got err %v; want %v", err, pricehistory.ErrNoObservations)
+	}
+}
+
+func TestRecordFailures(t *testing.T) {
+	store, err := pricehistory.Open(filepath.Join(t.TempDir(), "prices.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	prod := gpu(100)
+	prod.ASIN = ""
+
+	if err := store.Record(prod, start); !errors.Is(err, pricehistory.ErrNoASIN) {
+		t.Fatalf("got err %v; want %v", err, pricehistory.ErrNoASIN)
+	}
+}
+
+func gpu(price int64) product.Product {
+	return product.Product{
+		URL:          "https://www.amazon.com/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4",
+		ASIN:         "B08KWLMZV4",
+		CanonicalURL: "https://www.amazon.com/dp/B08KWLMZV4",
+		Name:         "MSI Gaming GeForce RTX 3070",
+		Price:        usd(price),
+	}
+}
+
+func usd(amount int64) money.Money {
+	return money.Money{Amount: amount, Currency: "USD"}
+}
@@ -0,0 +1,126 @@
+package product
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+type Error string
+
+const (
+	ErrNoASIN Error = "no ASIN found"
+)
+
+// asinMarkers are the path segments that are followed by the
+// ASIN on Amazon links, like /dp/ASIN or /gp/product/ASIN.
+var asinMarkers = map[string]struct{}{
+	"dp":            {},
+	"product":       {},
+	"offer-listing": {},
+	"d":             {},
+	"o":             {},
+	"ASIN":          {},
+	"obidos":        {},
+}
+
+// ParseASIN parses the ASIN (Amazon Standard Identification Number)
+// from the given link, which may be absolute or just a path. It supports
+// /dp/ASIN, /gp/product/ASIN, /gp/offer-listing/ASIN and short links
+// like amzn.com/ASIN. Short links that are redirects, like amzn.to,
+// have no ASIN on them, the page they redirect to must be parsed instead.
+func ParseASIN(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	if asin := strings.ToUpper(segments[0]); isShortLinkHost(u.Host) && isASIN(asin) {
+		return asin, nil
+	}
+
+	for i := 0; i < len(segments)-1; i++ {
+		if _, ok := asinMarkers[segments[i]]; !ok {
+			continue
+		}
+		if asin := strings.ToUpper(segments[i+1]); isASIN(asin) {
+			return asin, nil
+		}
+	}
+
+	if asin := strings.ToUpper(u.Query().Get("asin")); isASIN(asin) {
+		return asin, nil
+	}
+
+	return "", fmt.Errorf("%w on link %q", ErrNoASIN, link)
+}
+
+// CanonicalURL returns the canonical URL of the product on the given link,
+// in the form https://domain/dp/ASIN. Links to the same product on the
+// same domain always have the same canonical URL, no matter their form.
+func CanonicalURL(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	asin, err := ParseASIN(link)
+	if err != nil {
+		return "", err
+	}
+	return canonicalURL(u.Host, asin), nil
+}
+
+// Key returns a key that identifies the product on the given link,
+// its canonical URL, or the link itself when it has no ASIN.
+func Key(link string) string {
+	if canonical, err := CanonicalURL(link); err == nil {
+		return canonical
+	}
+	return link
+}
+
+func canonicalURL(domain, asin string) string {
+	return "https://" + domain + "/dp/" + asin
+}
+
+const (
+	asinSelector      = "input#ASIN"
+	canonicalSelector = `link[rel="canonical"]`
+)
+
+// parsePageASIN parses the ASIN from the product page, useful
+// when the link used to get the page has no ASIN on it.
+func parsePageASIN(doc *goquery.Document) (string, error) {
+	if asin, ok := doc.Find(asinSelector).Attr("value"); ok && isASIN(asin) {
+		return asin, nil
+	}
+	if canonical, ok := doc.Find(canonicalSelector).Attr("href"); ok {
+		return ParseASIN(canonical)
+	}
+	return "", ErrNoASIN
+}
+
+func isShortLinkHost(host string) bool {
+	host = strings.TrimPrefix(host, "www.")
+	return host == "amzn.com"
+}
+
+func isASIN(s string) bool {
+	if len(s) != 10 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (e Error) Error() string {
+	return string(e)
+}
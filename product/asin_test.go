@@ -0,0 +1,105 @@
+package product_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/katcipis/amazoner/product"
+)
+
+func TestParseASIN(t *testing.T) {
+	type Test struct {
+		link          string
+		wantASIN      string
+		wantCanonical string
+	}
+
+	tests := []Test{
+		{
+			link:          "https://www.amazon.com/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4/ref=sr_1_1?dchild=1",
+			wantASIN:      "B08KWLMZV4",
+			wantCanonical: "https://www.amazon.com/dp/B08KWLMZV4",
+		},
+		{
+			link:          "https://www.amazon.de/dp/B08KWLMZV4",
+			wantASIN:      "B08KWLMZV4",
+			wantCanonical: "https://www.amazon.de/dp/B08KWLMZV4",
+		},
+		{
+			link:          "https://www.amazon.com/gp/product/B08KWLMZV4?pf_rd_r=XYZ",
+			wantASIN:      "B08KWLMZV4",
+			wantCanonical: "https://www.amazon.com/dp/B08KWLMZV4",
+		},
+		{
+			link:          "https://www.amazon.com/gp/offer-listing/b08kwlmzv4/ref=dp_olp_new",
+			wantASIN:      "B08KWLMZV4",
+			wantCanonical: "https://www.amazon.com/dp/B08KWLMZV4",
+		},
+		{
+			link:          "https://www.amazon.co.uk/gp/aw/d/0131103628",
+			wantASIN:      "0131103628",
+			wantCanonical: "https://www.amazon.co.uk/dp/0131103628",
+		},
+		{
+			link:          "https://amzn.com/B08KWLMZV4",
+			wantASIN:      "B08KWLMZV4",
+			wantCanonical: "https://amzn.com/dp/B08KWLMZV4",
+		},
+		{
+			link:          "https://amzn.com/b08kwlmzv4",
+			wantASIN:      "B08KWLMZV4",
+			wantCanonical: "https://amzn.com/dp/B08KWLMZV4",
+		},
+		{
+			link:     "/PNY-GeForce-Gaming-Epic-X-Graphics/dp/B08HBJB7YD",
+			wantASIN: "B08HBJB7YD",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.link, func(t *testing.T) {
+			asin, err := product.ParseASIN(test.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if asin != test.wantASIN {
+				t.Errorf("got ASIN %q; want %q", asin, test.wantASIN)
+			}
+
+			if test.wantCanonical == "" {
+				return
+			}
+			canonical, err := product.CanonicalURL(test.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if canonical != test.wantCanonical {
+				t.Errorf("got canonical URL %q; want %q", canonical, test.wantCanonical)
+			}
+			if key := product.Key(test.link); key != test.wantCanonical {
+				t.Errorf("got key %q; want %q", key, test.wantCanonical)
+			}
+		})
+	}
+}
+
+func TestParseASINFailures(t *testing.T) {
+	links := []string{
+		"https://amzn.to/3lQ7Xyz",
+		"https://www.amazon.com/s?k=nvidia+rtx+3070",
+		"https://www.amazon.com/dp/B08KW",
+		"https://www.amazon.com/MSI-GeForce-256-Bit-Architecture-Graphics",
+	}
+
+	for _, link := range links {
+		t.Run(link, func(t *testing.T) {
+			_, err := product.ParseASIN(link)
+			if !errors.Is(err, product.ErrNoASIN) {
+				t.Fatalf("got err %v; want %v", err, product.ErrNoASIN)
+			}
+			if key := product.Key(link); key != link {
+				t.Errorf("got key %q; want the link itself", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package product
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/katcipis/amazoner/availability"
+	"github.com/katcipis/amazoner/marketplace"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/parser"
+)
+
+// parseDetails parses the optional details of the product page.
+// Details missing on the page are left with their zero value.
+func parseDetails(doc *goquery.Document, prod *Product) {
+	currency := localCurrency(prod.URL)
+	prod.Rating = parseRating(doc)
+	prod.Reviews = parseReviews(doc)
+	prod.Brand = parseBrand(doc)
+	prod.Images = parseImages(doc)
+	prod.Availability, _ = parser.ParseById(doc, "availability")
+	prod.SoldBy, prod.ShipsFrom = parseSeller(doc)
+	prod.Prime = doc.Find("#priceBadging_feature_div i.a-icon-prime, #buybox i.a-icon-prime").Length() > 0
+	prod.ListPrice = parseMoney(doc.Selection, "#price span.priceBlockStrikePriceString, #price span.a-text-strike", currency)
+	prod.DealPrice = parseMoney(doc.Selection, "#priceblock_dealprice", currency)
+	prod.Features = parseFeatures(doc)
+}
+
+// Stock returns the classified availability of the product,
+// on the language of its marketplace.
+func (p Product) Stock() availability.Availability {
+	link := p.CanonicalURL
+	if link == "" {
+		link = p.URL
+	}
+	domain := ""
+	if u, err := url.Parse(link); err == nil {
+		domain = u.Host
+	}
+	return availability.Classify(domain, p.Availability)
+}
+
+// InStock returns true if the product can be bought now,
+// see availability.Availability.InStock.
+func (p Product) InStock() bool {
+	return p.Stock().InStock()
+}
+
+// parseRating parses the star rating from texts like "4.6 out of 5 stars"
+// or "4,6 von 5 Sternen", returning zero if the product has no rating.
+func parseRating(doc *goquery.Document) float64 {
+	text, ok := doc.Find("#acrPopover").Attr("title")
+	if !ok {
+		text = doc.Find("#acrPopover span.a-icon-alt").First().Text()
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0
+	}
+
+	rating, err := strconv.ParseFloat(strings.Replace(fields[0], ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+	return rating
+}
+
+// parseReviews parses the review count from texts like "2,317 ratings"
+// or "2.317 Sternebewertungen".
+func parseReviews(doc *goquery.Document) int {
+	text := doc.Find("#acrCustomerReviewText").First().Text()
+
+	digits := strings.Builder{}
+	for _, field := range strings.Fields(text) {
+		for _, r := range field {
+			if unicode.IsDigit(r) {
+				digits.WriteRune(r)
+			}
+		}
+		if digits.Len() > 0 {
+			break
+		}
+	}
+
+	reviews, err := strconv.Atoi(digits.String())
+	if err != nil {
+		return 0
+	}
+	return reviews
+}
+
+// parseBrand parses the brand from the product overview or from
+// the byline, like "Brand: MSI" or "Visit the MSI Store".
+func parseBrand(doc *goquery.Document) string {
+	brand := strings.TrimSpace(doc.Find("#productOverview_feature_div tr.po-brand td span.po-break-word").Text())
+	if brand != "" {
+		return brand
+	}
+
+	byline := strings.TrimSpace(doc.Find("#bylineInfo").Text())
+	if i := strings.Index(byline, ":"); i >= 0 {
+		return strings.TrimSpace(byline[i+1:])
+	}
+	if strings.HasPrefix(byline, "Visit the ") && strings.HasSuffix(byline, " Store") {
+		return strings.TrimSuffix(strings.TrimPrefix(byline, "Visit the "), " Store")
+	}
+	return byline
+}
+
+// parseImages parses the URLs of the main image of the product,
+// the high resolution one first and then the others from the
+// largest to the smallest.
+func parseImages(doc *goquery.Document) []string {
+	img := doc.Find("#landingImage, #imgBlkFront").First()
+	images := []string{}
+
+	if hires, ok := img.Attr("data-old-hires"); ok && hires != "" {
+		images = append(images, hires)
+	}
+
+	if dynamic, ok := img.Attr("data-a-dynamic-image"); ok {
+		sizes := map[string][]int{}
+		if err := json.Unmarshal([]byte(dynamic), &sizes); err == nil {
+			urls := make([]string, 0, len(sizes))
+			for url := range sizes {
+				urls = append(urls, url)
+			}
+			sort.Slice(urls, func(i, j int) bool {
+				return width(sizes[urls[i]]) > width(sizes[urls[j]])
+			})
+			images = append(images, urls...)
+		}
+	}
+
+	if src, ok := img.Attr("src"); ok && src != "" {
+		images = append(images, src)
+	}
+
+	if len(images) == 0 {
+		return nil
+	}
+	return removeDuplicates(images)
+}
+
+// parseSeller parses who sells and who ships the product, from the
+// tabular buybox or from texts like "Ships from and sold by Amazon.com."
+// and "Sold by GPU Outlet and Fulfilled by Amazon.".
+func parseSeller(doc *goquery.Document) (string, string) {
+	tabular := func(name string) string {
+		sel := doc.Find(`#tabular-buybox .tabular-buybox-text[tabular-attribute-name="` + name + `"]`)
+		return strings.TrimSpace(sel.First().Text())
+	}
+
+	soldBy, shipsFrom := tabular("Sold by"), tabular("Ships from")
+	if soldBy != "" || shipsFrom != "" {
+		return soldBy, shipsFrom
+	}
+
+	info, ok := parser.ParseById(doc, "merchant-info")
+	if !ok {
+		return "", ""
+	}
+	info = strings.TrimSuffix(info, ".")
+
+	if seller := strings.TrimPrefix(info, "Ships from and sold by "); seller != info {
+		return seller, seller
+	}
+
+	if strings.HasPrefix(info, "Sold by ") {
+		soldBy = strings.TrimPrefix(info, "Sold by ")
+		if i := strings.Index(soldBy, " and Fulfilled by "); i >= 0 {
+			shipsFrom = soldBy[i+len(" and Fulfilled by "):]
+			soldBy = soldBy[:i]
+		} else if i := strings.Index(soldBy, " and ships from "); i >= 0 {
+			shipsFrom = soldBy[i+len(" and ships from "):]
+			soldBy = soldBy[:i]
+		}
+	}
+
+	if seller := strings.TrimSpace(doc.Find("#sellerProfileTriggerId").Text()); seller != "" {
+		soldBy = seller
+	}
+	return soldBy, shipsFrom
+}
+
+// parseFeatures parses the bullet features of the product.
+func parseFeatures(doc *goquery.Document) []string {
+	var features []string
+
+	doc.Find("#feature-bullets li:not(.aok-hidden) span.a-list-item").Each(func(i int, s *goquery.Selection) {
+		feature := strings.Join(strings.Fields(s.Text()), " ")
+		if feature != "" {
+			features = append(features, feature)
+		}
+	})
+
+	return features
+}
+
+// parseMoney parses the money of the selected element, on the given
+// local currency, see money.ParseIn.
+func parseMoney(s *goquery.Selection, cssSelector, currency string) money.Money {
+	text := s.Find(cssSelector).First().Text()
+	if text == "" {
+		return money.Money{}
+	}
+	m, err := money.ParseIn(text, currency)
+	if err != nil {
+		return money.Money{}
+	}
+	return m
+}
+
+// localCurrency returns the currency of the marketplace of the link,
+// which is empty if the marketplace is unknown.
+func localCurrency(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return domainCurrency(u.Host)
+}
+
+func domainCurrency(domain string) string {
+	m, ok := marketplace.Lookup(domain)
+	if !ok {
+		return ""
+	}
+	return m.Currency
+}
+
+func width(size []int) int {
+	if len(size) == 0 {
+		return 0
+	}
+	return size[0]
+}
+
+func removeDuplicates(values []string) []string {
+	uniq := map[string]struct{}{}
+	res := []string{}
+
+	for _, v := range values {
+		if _, ok := uniq[v]; ok {
+			continue
+		}
+		uniq[v] = struct{}{}
+		res = append(res, v)
+	}
+
+	return res
+}
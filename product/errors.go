@@ -0,0 +1,108 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ErrParse is a failure parsing a product page, any *ParseError is
+	// ErrParse when checked with errors.Is.
+	ErrParse Error = "cant parse product"
+)
+
+// ParseError is a failure parsing a field of a product page,
+// with the CSS selectors that selected nothing useful.
+type ParseError struct {
+	URL   string
+	Field string
+	// Selectors are the CSS selectors tried to parse the field.
+	Selectors []string
+	// Err is what caused the failure, if any.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	msg := "cant parse product " + e.Field
+	if len(e.Selectors) > 0 {
+		msg += fmt.Sprintf(" (selectors %s)", strings.Join(e.Selectors, ", "))
+	}
+	if e.Err != nil {
+		msg += ":\n" + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == ErrParse
+}
+
+// IsPriceMissing reports whether the product was got without its
+// price, like Get does for products out of stock, so it is known
+// with its availability despite the error.
+func IsPriceMissing(prod Product, err error) bool {
+	var parseErr *ParseError
+	return prod.URL != "" && errors.As(err, &parseErr) && parseErr.Field == "price"
+}
+
+// URLError is a failure getting the product on URL.
+type URLError struct {
+	URL string
+	Err error
+}
+
+func (e *URLError) Error() string {
+	return fmt.Sprintf("url %q : %v", e.URL, e.Err)
+}
+
+func (e *URLError) Unwrap() error {
+	return e.Err
+}
+
+// Errors are multiple errors, like the errors of each URL when getting
+// multiple products. It is any of the errors when checked with errors.Is
+// and errors.As, so it is possible to check if some product failed because
+// of a captcha with errors.Is(err, fetch.ErrCaptcha) and to get the errors
+// of each URL by ranging over it.
+type Errors []error
+
+func (e Errors) Error() string {
+	errmsgs := make([]string, len(e))
+	for i, err := range e {
+		errmsgs[i] = err.Error()
+	}
+	return strings.Join(errmsgs, "\n")
+}
+
+// Is reports whether any of the errors is the target.
+func (e Errors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches the target.
+func (e Errors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// toErr returns the errors as Errors, or nil if there are none.
+func toErr(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return Errors(errs)
+}
@@ -0,0 +1,3 @@
+package product
+
+var ParseFees = parseFees
@@ -0,0 +1,97 @@
+package product
+
+import (
+	"context"
+	"sync"
+
+	"github.com/katcipis/amazoner/fetch"
+)
+
+// Fetcher gets the details of multiple products concurrently.
+// The rate of requests is limited by the client Limiter, which
+// is shared by all workers.
+type Fetcher struct {
+	// Client is used to get the products, if nil a default client is used.
+	Client *fetch.Client
+	// Workers is how many products are fetched concurrently,
+	// defaults to DefaultWorkers.
+	Workers int
+}
+
+// Result is the result of getting the details of a single product.
+type Result struct {
+	URL     string
+	Product Product
+	Err     error
+}
+
+const DefaultWorkers = 4
+
+// Fetch gets all products details from the given URLs.
+// It is possible to have results and an error, which indicates
+// a partial result. The error has a *URLError for each failed URL.
+// If the context is cancelled the products got so far are returned
+// with the context error.
+func (f *Fetcher) Fetch(ctx context.Context, urls []string) ([]Product, error) {
+	var errs []error
+	var prods []Product
+
+	for _, res := range f.FetchAll(ctx, urls) {
+		if res.Err != nil {
+			errs = append(errs, &URLError{URL: res.URL, Err: res.Err})
+			continue
+		}
+		prods = append(prods, res.Product)
+	}
+
+	if ctx.Err() != nil {
+		return prods, ctx.Err()
+	}
+	return prods, toErr(errs)
+}
+
+// FetchAll gets all products details from the given URLs, returning
+// one result for each URL on the same order of the given URLs.
+// URLs not fetched because the context was cancelled have
+// the context error as result.
+func (f *Fetcher) FetchAll(ctx context.Context, urls []string) []Result {
+	results := make([]Result, len(urls))
+	indexes := make(chan int)
+
+	workers := f.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if workers > len(urls) {
+		workers = len(urls)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				prod, err := GetContext(ctx, f.Client, urls[i])
+				results[i] = Result{URL: urls[i], Product: prod, Err: err}
+			}
+		}()
+	}
+
+	for i, url := range urls {
+		if ctx.Err() != nil {
+			results[i] = Result{URL: url, Err: ctx.Err()}
+			continue
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			results[i] = Result{URL: url, Err: ctx.Err()}
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
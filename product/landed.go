@@ -0,0 +1,137 @@
+package product
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/katcipis/amazoner/money"
+)
+
+// Words used on shipping and import fees lines, on the
+// languages of the marketplaces.
+var (
+	freeWords = []string{"free", "gratis", "kostenlos", "gratuit", "grátis"}
+
+	shippingWords = []string{
+		"shipping", "delivery", "versand", "lieferung", "verzending",
+		"bezorging", "livraison", "envío", "spedizione", "frete",
+	}
+
+	importWords = []string{
+		"import", "einfuhr", "invoer", "importation",
+		"importación", "importazione", "importação",
+	}
+)
+
+// shippingSelectors select the shipping line of the buy box price.
+var shippingSelectors = []string{
+	"#ourprice_shippingmessage",
+	"#dealprice_shippingmessage",
+	"#price-shipping-message",
+	"#deliveryMessageMirId",
+}
+
+// importFeesSelectors select the import fees line of the buy box price,
+// shown when shipping to another country.
+var importFeesSelectors = []string{
+	"#exports_desktop_qualifiedBuybox_tlc_feature_div",
+	"#amazonGlobal_feature_div",
+}
+
+// LandedPrice returns the price of the product plus its
+// shipping cost and import fees.
+func (p Product) LandedPrice() (money.Money, error) {
+	return landedPrice(p.Price, p.Shipping, p.ImportFees)
+}
+
+// LandedPrice returns the price of the offer plus its
+// shipping cost and import fees.
+func (o Offer) LandedPrice() (money.Money, error) {
+	return landedPrice(o.Price, o.Shipping, o.ImportFees)
+}
+
+func landedPrice(price, shipping, importFees money.Money) (money.Money, error) {
+	total, err := price.Add(shipping)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return total.Add(importFees)
+}
+
+// parseBuyBoxFees parses the shipping cost and import fees
+// of the buy box price of the product page, on the given local currency.
+func parseBuyBoxFees(doc *goquery.Document, currency string) (money.Money, money.Money) {
+	lines := []string{}
+	for _, selector := range shippingSelectors {
+		lines = append(lines, text(doc.Find(selector)))
+	}
+
+	shipping, _ := parseFees(strings.Join(lines, " + "), currency)
+
+	lines = lines[:0]
+	for _, selector := range importFeesSelectors {
+		lines = append(lines, text(doc.Find(selector)))
+	}
+
+	// Amazon usually shows the shipping and import fees together,
+	// so the shipping found on this line is part of the import fees.
+	importShipping, importFees := parseFees(strings.Join(lines, " + "), currency)
+	if !importShipping.IsZero() && importFees.IsZero() {
+		importFees = importShipping
+	}
+
+	return shipping, importFees
+}
+
+// parseFees parses the shipping cost and import fees from fee lines
+// like "+ $14.99 shipping", "FREE delivery: Wednesday, Dec 9" or
+// "$67.73 Shipping & Import Fees Deposit to Netherlands". When the
+// shipping and import fees are shown together, with a single amount,
+// the amount is returned as import fees.
+// Fees that are not found are zero. Amounts are parsed on the given
+// local currency, see money.ParseIn.
+func parseFees(line, currency string) (money.Money, money.Money) {
+	var (
+		shipping     money.Money
+		importFees   money.Money
+		lastShipping bool
+	)
+
+	parts := strings.FieldsFunc(line, func(r rune) bool {
+		return r == '+' || r == '&'
+	})
+
+	for _, part := range parts {
+		lower := strings.ToLower(part)
+		isImport := containsAny(lower, importWords)
+		isShipping := containsAny(lower, shippingWords)
+
+		amount, err := money.ParseIn(part, currency)
+		hasAmount := err == nil && amount.Currency != "" && !containsAny(lower, freeWords)
+
+		switch {
+		case hasAmount && isImport:
+			importFees = amount
+		case hasAmount && isShipping:
+			if shipping.IsZero() {
+				shipping = amount
+			}
+		case isImport && lastShipping:
+			// Like "$67.73 Shipping & Import Fees Deposit"
+			importFees, shipping = shipping, money.Money{}
+		}
+
+		lastShipping = hasAmount && isShipping && !isImport
+	}
+
+	return shipping, importFees
+}
+
+func containsAny(s string, words []string) bool {
+	for _, word := range words {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
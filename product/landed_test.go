@@ -0,0 +1,147 @@
+package product_test
+
+import (
+	"testing"
+
+	"github.com/katcipis/amazoner/amazontest"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+)
+
+func TestLandedPrice(t *testing.T) {
+	type Test struct {
+		name           string
+		path           string
+		page           string
+		wantShipping   money.Money
+		wantImportFees money.Money
+		wantLanded     money.Money
+	}
+
+	tests := []Test{
+		{
+			name:       "FreeShipping",
+			path:       "/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4",
+			page:       amazontest.Fixture("product_priceblock_ourprice.html"),
+			wantLanded: money.Money{Amount: 123456, Currency: "USD"},
+		},
+		{
+			name:         "OtherSellersShipping",
+			path:         "/MSI-GeForce-RTX-2060-Architecture/dp/B07MQ36Z6L",
+			page:         amazontest.Fixture("product_olp_upd_new_used.html"),
+			wantShipping: money.Money{Amount: 999, Currency: "USD"},
+			wantLanded:   money.Money{Amount: 62249, Currency: "USD"},
+		},
+		{
+			name:           "ImportFees",
+			path:           "/MSI-RTX-2070-Super-Architecture/dp/B0856BVRFL",
+			page:           amazontest.Fixture("product_import_fees.html"),
+			wantImportFees: money.Money{Amount: 6773, Currency: "USD"},
+			wantLanded:     money.Money{Amount: 100772, Currency: "USD"},
+		},
+		{
+			name:         "OfferListing",
+			path:         "/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWX",
+			page:         amazontest.Fixture("product_available_from_sellers.html"),
+			wantShipping: money.Money{Amount: 1499, Currency: "USD"},
+			wantLanded:   money.Money{Amount: 37498, Currency: "USD"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := amazontest.NewServer(t)
+			server.Handle(test.path, amazontest.Page{File: test.page})
+			server.Handle("/gp/offer-listing/B07YXPVBWX", amazontest.Page{
+				File: amazontest.Fixture("offer_listing.html"),
+			})
+
+			p, err := product.Get(client, server.URL+test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Shipping != test.wantShipping {
+				t.Errorf("got shipping %v; want %v", p.Shipping, test.wantShipping)
+			}
+			if p.ImportFees != test.wantImportFees {
+				t.Errorf("got import fees %v; want %v", p.ImportFees, test.wantImportFees)
+			}
+
+			landed, err := p.LandedPrice()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if landed != test.wantLanded {
+				t.Errorf("got landed price %v; want %v", landed, test.wantLanded)
+			}
+		})
+	}
+}
+
+func TestParseFees(t *testing.T) {
+	type Test struct {
+		line           string
+		currency       string
+		wantShipping   money.Money
+		wantImportFees money.Money
+	}
+
+	usd := func(amount int64) money.Money {
+		return money.Money{Amount: amount, Currency: "USD"}
+	}
+	eur := func(amount int64) money.Money {
+		return money.Money{Amount: amount, Currency: "EUR"}
+	}
+
+	tests := []Test{
+		{line: "FREE Shipping"},
+		{line: "FREE Shipping on orders over $25.00"},
+		{line: "FREE delivery: Wednesday, Dec 9"},
+		{line: "Arrives between Dec 10-15."},
+		{
+			line:         "+ $14.99 shipping",
+			wantShipping: usd(1499),
+		},
+		{
+			line:         "$24.99 delivery December 14 - 18",
+			wantShipping: usd(2499),
+		},
+		{
+			line:           "$67.73 Shipping & Import Fees Deposit to Netherlands Details",
+			wantImportFees: usd(6773),
+		},
+		{
+			line:           "+ $20.24 shipping + $47.49 estimated import fees deposit",
+			wantShipping:   usd(2024),
+			wantImportFees: usd(4749),
+		},
+		{
+			line:         "+ $14.99 shipping",
+			currency:     "CAD",
+			wantShipping: money.Money{Amount: 1499, Currency: "CAD"},
+		},
+		{
+			line:         "+ 4,99 € Versand",
+			wantShipping: eur(499),
+		},
+		{
+			line:           "12,50 € Versand und Einfuhrgebühren",
+			wantImportFees: eur(1250),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.line+test.currency, func(t *testing.T) {
+			shipping, importFees := product.ParseFees(test.line, test.currency)
+			if shipping != test.wantShipping {
+				t.Errorf("got shipping %v; want %v", shipping, test.wantShipping)
+			}
+			if importFees != test.wantImportFees {
+				t.Errorf("got import fees %v; want %v", importFees, test.wantImportFees)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package product
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+)
+
+// Offer is an offer of a product by one of its sellers.
+type Offer struct {
+	Price money.Money
+	// Shipping is the shipping cost, zero when shipping is free.
+	Shipping money.Money
+	// ImportFees is the import fees deposit charged when shipping
+	// to another country. When Amazon shows the shipping and import
+	// fees together they are all on ImportFees.
+	ImportFees money.Money
+	Condition  string
+	Seller     string
+	// SellerRating is the percentage of positive ratings of the
+	// seller, zero when unknown, like for Amazon itself.
+	SellerRating int
+	// FBA is true if the offer is fulfilled by Amazon.
+	FBA bool
+	// Delivery is the delivery estimate, like "Arrives: Dec 9 - 11".
+	Delivery string
+}
+
+// OfferSelector selects the offers on the offer listing page and
+// on the all offers display, on the same order of ParseOffers.
+const OfferSelector = "#aod-pinned-offer, #aod-offer, #olpOfferList div.olpOffer"
+
+const ErrNoOffers Error = "no offers found"
+
+// Offers gets all the offers of the product on the given link,
+// ignoring the offers without a price.
+// If the client is nil a default client is used.
+func Offers(c *fetch.Client, link string) ([]Offer, error) {
+	return OffersContext(context.Background(), c, link)
+}
+
+// OffersContext is like Offers but with a context that can cancel the requests.
+func OffersContext(ctx context.Context, c *fetch.Client, link string) ([]Offer, error) {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	asin, err := ParseASIN(link)
+	if err != nil {
+		return nil, err
+	}
+
+	// The offer listing page is being replaced by the all offers
+	// display, which is loaded by the product page on demand.
+	pages := []string{
+		"/gp/offer-listing/" + asin,
+		"/gp/aod/ajax/?asin=" + asin,
+	}
+
+	errs := []error{}
+	for _, page := range pages {
+		offers, err := getOffers(ctx, c, linkURL.Scheme+"://"+linkURL.Host+page)
+		if err == nil {
+			return offers, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, toErr(errs)
+}
+
+// ParseOffers parses the offers of the offer listing page or of the
+// all offers display, one for each element selected by OfferSelector.
+// Offers whose price can't be parsed have a zero price.
+// Prices with only the $ symbol are parsed as USD, use ParseOffersIn
+// for pages of marketplaces with other dollar currencies.
+func ParseOffers(doc *goquery.Document) []Offer {
+	return ParseOffersIn(doc, "")
+}
+
+// ParseOffersIn is like ParseOffers, but the page is from a marketplace
+// with the given local currency, see money.ParseIn.
+func ParseOffersIn(doc *goquery.Document, currency string) []Offer {
+	offers := []Offer{}
+
+	doc.Find(OfferSelector).Each(func(i int, s *goquery.Selection) {
+		if s.HasClass("olpOffer") {
+			offers = append(offers, parseOLPOffer(s, currency))
+			return
+		}
+		offers = append(offers, parseAODOffer(s, currency))
+	})
+
+	return offers
+}
+
+// BestOffer returns the index of the best offer, the new one with
+// the cheapest landed price, or the cheapest one if there are no new offers.
+// Offers without a price are ignored.
+// It returns false if there are no offers.
+func BestOffer(offers []Offer) (int, bool) {
+	best := -1
+	bestNew := -1
+
+	cheaper := func(i, j int) bool {
+		if j < 0 {
+			return true
+		}
+		a, err := offers[i].LandedPrice()
+		if err != nil {
+			return false
+		}
+		b, err := offers[j].LandedPrice()
+		if err != nil {
+			return true
+		}
+		cmp, err := a.Cmp(b)
+		return err == nil && cmp < 0
+	}
+
+	for i, offer := range offers {
+		if offer.Price.IsZero() {
+			continue
+		}
+		if cheaper(i, best) {
+			best = i
+		}
+		if isNew(offer.Condition) && cheaper(i, bestNew) {
+			bestNew = i
+		}
+	}
+
+	if bestNew >= 0 {
+		return bestNew, true
+	}
+	return best, best >= 0
+}
+
+func getOffers(ctx context.Context, c *fetch.Client, link string) ([]Offer, error) {
+	responseBody, err := doRequest(ctx, c, link)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(responseBody)
+	if err != nil {
+		return nil, err
+	}
+
+	offers := []Offer{}
+	for _, offer := range ParseOffersIn(doc, localCurrency(link)) {
+		if !offer.Price.IsZero() {
+			offers = append(offers, offer)
+		}
+	}
+
+	if len(offers) == 0 {
+		return nil, fmt.Errorf("%w on %q", ErrNoOffers, link)
+	}
+	return offers, nil
+}
+
+func parseOLPOffer(s *goquery.Selection, currency string) Offer {
+	shipping, importFees := parseFees(text(s.Find(".olpShippingInfo"))+" + "+text(s.Find(".olpEstimatedTaxText")), currency)
+
+	seller := text(s.Find(".olpSellerName a"))
+	if seller == "" {
+		// Amazon itself is shown as a logo
+		seller, _ = s.Find(".olpSellerName img").Attr("alt")
+	}
+
+	return Offer{
+		Price:        parseMoney(s, ".olpOfferPrice", currency),
+		Shipping:     shipping,
+		ImportFees:   importFees,
+		Condition:    text(s.Find(".olpCondition")),
+		Seller:       seller,
+		SellerRating: parsePercentage(text(s.Find(".olpSellerColumn b"))),
+		FBA:          s.Find(".olpBadge, .olpFbaPopoverTrigger").Length() > 0,
+		Delivery:     text(s.Find(".olpDeliveryColumn .olpFastTrack li").First()),
+	}
+}
+
+func parseAODOffer(s *goquery.Selection, currency string) Offer {
+	delivery := text(s.Find(`#mir-layout-DELIVERY_BLOCK, .aod-delivery-promise`).First())
+	shipping, importFees := parseFees(delivery, currency)
+
+	shipsFrom := text(s.Find("#aod-offer-shipsFrom .a-color-base"))
+	seller := text(s.Find("#aod-offer-soldBy a"))
+	if seller == "" {
+		seller = text(s.Find("#aod-offer-soldBy .a-color-base"))
+	}
+
+	return Offer{
+		Price:        parseMoney(s, ".a-price .a-offscreen", currency),
+		Shipping:     shipping,
+		ImportFees:   importFees,
+		Condition:    text(s.Find("#aod-offer-heading h5")),
+		Seller:       seller,
+		SellerRating: parsePercentage(text(s.Find("#aod-offer-seller-rating"))),
+		FBA:          strings.HasPrefix(shipsFrom, "Amazon"),
+		Delivery:     delivery,
+	}
+}
+
+// parsePercentage parses the first percentage of the text,
+// like 93 from "93% positive".
+func parsePercentage(s string) int {
+	i := strings.Index(s, "%")
+	if i < 0 {
+		return 0
+	}
+
+	start := i
+	for start > 0 && s[start-1] >= '0' && s[start-1] <= '9' {
+		start--
+	}
+
+	v, err := strconv.Atoi(s[start:i])
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// newConditions are the condition of new offers on the
+// languages of the marketplaces, all lower case.
+var newConditions = map[string]struct{}{
+	"new": {}, "neu": {}, "nieuw": {}, "neuf": {},
+	"nuevo": {}, "nuovo": {}, "novo": {},
+}
+
+func isNew(condition string) bool {
+	_, ok := newConditions[strings.ToLower(strings.TrimSpace(condition))]
+	return ok
+}
+
+func text(s *goquery.Selection) string {
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
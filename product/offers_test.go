@@ -0,0 +1,162 @@
+package product_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/katcipis/amazoner/amazontest"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+)
+
+func TestOffers(t *testing.T) {
+	const path = "/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWX"
+
+	usd := func(amount int64) money.Money {
+		return money.Money{Amount: amount, Currency: "USD"}
+	}
+	eur := func(amount int64) money.Money {
+		return money.Money{Amount: amount, Currency: "EUR"}
+	}
+
+	type Test struct {
+		name     string
+		pagePath string
+		page     string
+		want     []product.Offer
+		wantBest int
+	}
+
+	tests := []Test{
+		{
+			name:     "OfferListing",
+			pagePath: "/gp/offer-listing/B07YXPVBWX",
+			page:     amazontest.Fixture("offer_listing.html"),
+			want: []product.Offer{
+				{
+					Price:        usd(35999),
+					Shipping:     usd(1499),
+					Condition:    "New",
+					Seller:       "GPU Outlet",
+					SellerRating: 93,
+					Delivery:     "Arrives between Dec 10-15.",
+				},
+				{
+					Price:        usd(37900),
+					Condition:    "New",
+					Seller:       "Tech Deals Direct",
+					SellerRating: 98,
+					FBA:          true,
+					Delivery:     "Arrives: Dec 9 - 11",
+				},
+			},
+			wantBest: 0,
+		},
+		{
+			name:     "AllOffersDisplay",
+			pagePath: "/gp/aod/ajax/?asin=B07YXPVBWX",
+			page:     amazontest.Fixture("aod_offers.html"),
+			want: []product.Offer{
+				{
+					Price:     usd(38999),
+					Condition: "New",
+					Seller:    "Amazon.com",
+					FBA:       true,
+					Delivery:  "FREE delivery Thursday, December 10",
+				},
+				{
+					Price:        usd(32999),
+					Shipping:     usd(2499),
+					Condition:    "Used - Like New",
+					Seller:       "Refurb Kings",
+					SellerRating: 87,
+					Delivery:     "$24.99 delivery December 14 - 18",
+				},
+				{
+					Price:        usd(37250),
+					Condition:    "New",
+					Seller:       "Tech Deals Direct",
+					SellerRating: 98,
+					FBA:          true,
+					Delivery:     "FREE delivery Friday, December 11",
+				},
+			},
+			wantBest: 2,
+		},
+		{
+			name:     "AllOffersDisplayGerman",
+			pagePath: "/gp/aod/ajax/?asin=B07YXPVBWX",
+			page:     amazontest.Fixture("aod_offers_de.html"),
+			want: []product.Offer{
+				{
+					Price:     eur(38999),
+					Condition: "Neu",
+					Seller:    "Amazon.de",
+					FBA:       true,
+					Delivery:  "KOSTENLOSE Lieferung Donnerstag, 10. Dezember",
+				},
+				{
+					Price:        eur(29999),
+					Shipping:     eur(499),
+					Condition:    "Gebraucht - Wie neu",
+					Seller:       "Refurb Kings",
+					SellerRating: 87,
+					Delivery:     "4,99 € Lieferung 14. - 18. Dezember",
+				},
+				{
+					Price:        eur(37250),
+					Condition:    "Neu",
+					Seller:       "Tech Deals Direct",
+					SellerRating: 98,
+					FBA:          true,
+					Delivery:     "KOSTENLOSE Lieferung Freitag, 11. Dezember",
+				},
+			},
+			// The cheapest new offer, not the cheaper used one
+			wantBest: 2,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := amazontest.NewServer(t)
+			server.Handle(test.pagePath, amazontest.Page{File: test.page})
+
+			got, err := product.Offers(client, server.URL+path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got offers:\n%+v\nwant:\n%+v", got, test.want)
+			}
+
+			best, ok := product.BestOffer(got)
+			if !ok || best != test.wantBest {
+				t.Fatalf("got best offer %d, %t; want %d", best, ok, test.wantBest)
+			}
+		})
+	}
+}
+
+func TestOffersFailures(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/gp/offer-listing/B07YXPVBWX", amazontest.Page{File: amazontest.Fixture("product_available_from_sellers.html")})
+
+	_, err := product.Offers(client, server.URL+"/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWX")
+	if err == nil {
+		t.Fatal("want error when there are no offers")
+	}
+
+	_, err = product.Offers(client, server.URL+"/MSI-Twin-Frozr-Architecture-Overclocked-Graphics")
+	if !errors.Is(err, product.ErrNoASIN) {
+		t.Fatalf("got err %v; want %v", err, product.ErrNoASIN)
+	}
+
+	if _, ok := product.BestOffer(nil); ok {
+		t.Fatal("want no best offer without offers")
+	}
+}
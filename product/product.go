@@ -1,108 +1,197 @@
 package product
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"net/http"
 	"net/url"
-	"path/filepath"
-	"regexp"
 	"sort"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/katcipis/amazoner/header"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
 )
 
 type Product struct {
-	URL   string
-	Name  string
-	Price float64 // Yeah representing money as float is not an good idea in general
+	URL string
+	// ASIN is the Amazon Standard Identification Number of the product.
+	ASIN string
+	// CanonicalURL is the URL that identifies the product, in the
+	// form https://domain/dp/ASIN.
+	CanonicalURL string
+	Name         string
+	// Price is the price the product can be bought for.
+	Price money.Money
+	// Shipping is the shipping cost, zero when shipping is free.
+	Shipping money.Money
+	// ImportFees is the import fees deposit charged when shipping
+	// to another country. When Amazon shows the shipping and import
+	// fees together they are all on ImportFees.
+	ImportFees money.Money
+
+	// The details below are optional, having their zero value
+	// when they are not available on the product page.
+
+	// Rating is the average star rating, from 1 to 5.
+	Rating float64
+	// Reviews is how many customers rated the product.
+	Reviews int
+	Brand   string
+	// Images are the URLs of the main image of the product,
+	// on different resolutions, the highest resolution first.
+	Images []string
+	// Availability is the availability text, like "In Stock.".
+	Availability string
+	SoldBy       string
+	ShipsFrom    string
+	// Prime is true if the product is eligible for Prime.
+	Prime bool
+	// ListPrice is the price suggested by the manufacturer,
+	// usually shown striked through on discounts.
+	ListPrice money.Money
+	// DealPrice is the price of an ongoing deal.
+	DealPrice money.Money
+	// Features are the bullet points describing the product.
+	Features []string
 }
 
-func Get(link string) (Product, error) {
-	responseBody, err := doRequest(link)
+// Get gets the product details from the given link using the given client.
+// If the client is nil a default client is used.
+//
+// If the price can't be parsed, like for products out of stock, the
+// product is returned without price, with its availability and other
+// details, together with the *ParseError of the price.
+func Get(c *fetch.Client, link string) (Product, error) {
+	return GetContext(context.Background(), c, link)
+}
+
+// GetContext is like Get but with a context that can cancel the request.
+func GetContext(ctx context.Context, c *fetch.Client, link string) (Product, error) {
+	responseBody, err := doRequest(ctx, c, link)
 	if err != nil {
 		return Product{}, err
 	}
-	return parseProduct(responseBody, link)
+	return parseProduct(ctx, c, responseBody, link)
 }
 
 // GetProducts gets all products details from the given URLs.
 // It is possible to have results and an error, which indicates
 // a partial result.
-func GetProducts(urls []string) ([]Product, error) {
-	var errs []error
-	var prods []Product
+func GetProducts(c *fetch.Client, urls []string) ([]Product, error) {
+	return GetProductsContext(context.Background(), c, urls)
+}
 
-	for _, url := range urls {
-		product, err := Get(url)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("url %q : %v", url, err))
-			continue
-		}
-		prods = append(prods, product)
+// GetProductsContext is like GetProducts but with a context that can
+// cancel the remaining requests. If the context is cancelled the products
+// got so far are returned with the context error.
+// The products are fetched concurrently, use a Fetcher to control
+// how many products are fetched concurrently.
+func GetProductsContext(ctx context.Context, c *fetch.Client, urls []string) ([]Product, error) {
+	f := &Fetcher{Client: c}
+	return f.Fetch(ctx, urls)
+}
+
+// ParsePrice parses the price from the given product page, the
+// client is used to navigate to other pages if necessary.
+func ParsePrice(c *fetch.Client, doc *goquery.Document, link string) (money.Money, error) {
+	return ParsePriceContext(context.Background(), c, doc, link)
+}
+
+// ParsePriceContext is like ParsePrice but with a context that can
+// cancel the navigation to other pages.
+func ParsePriceContext(ctx context.Context, c *fetch.Client, doc *goquery.Document, link string) (money.Money, error) {
+	offer, err := ParseBuyingOfferContext(ctx, c, doc, link)
+	if err != nil {
+		return money.Money{}, err
 	}
+	return offer.Price, nil
+}
 
-	return prods, toErr(errs)
+// ParseBuyingOffer parses the offer the product would be bought for
+// from the given product page, with its price, shipping cost and import
+// fees. The client is used to navigate to other pages if necessary.
+func ParseBuyingOffer(c *fetch.Client, doc *goquery.Document, link string) (Offer, error) {
+	return ParseBuyingOfferContext(context.Background(), c, doc, link)
 }
 
-func ParsePrice(doc *goquery.Document, link string) (float64, error) {
+// ParseBuyingOfferContext is like ParseBuyingOffer but with a context
+// that can cancel the navigation to other pages.
+func ParseBuyingOfferContext(ctx context.Context, c *fetch.Client, doc *goquery.Document, link string) (Offer, error) {
 	// FIXME: probably just exposing Get or a Parse would be better
 	// instead of these very specific parsing functions.
 
+	currency := localCurrency(link)
 	errs := []error{}
-	parse := func(cssSelector string) (float64, bool) {
+	selectors := []string{}
+	parse := func(cssSelector string) (money.Money, bool) {
+		selectors = append(selectors, cssSelector)
 		moneyText := doc.Find(cssSelector).Text()
 		if moneyText == "" {
 			errs = append(errs, fmt.Errorf("selector %q selected nothing", cssSelector))
-			return 0, false
+			return money.Money{}, false
 		}
-		price, err := parseMoney(moneyText)
+		price, err := money.ParseIn(moneyText, currency)
 		if err != nil {
 			errs = append(errs, err)
-			return 0, false
+			return money.Money{}, false
 		}
 		return price, true
 	}
 
+	buyBoxOffer := func(price money.Money) Offer {
+		shipping, importFees := parseBuyBoxFees(doc, currency)
+		return Offer{Price: price, Shipping: shipping, ImportFees: importFees}
+	}
+
+	// The other sellers prices have their fees beside them
+	otherSellersOffer := func(price money.Money, cssSelector string) Offer {
+		shipping, importFees := parseFees(text(doc.Find(cssSelector)), currency)
+		return Offer{Price: price, Shipping: shipping, ImportFees: importFees}
+	}
+
 	if price, ok := parse("#price_inside_buybox"); ok {
-		return price, nil
+		return buyBoxOffer(price), nil
 	}
 
 	if price, ok := parse("#priceblock_ourprice"); ok {
-		return price, nil
+		return buyBoxOffer(price), nil
+	}
+
+	if price, ok := parse("#priceblock_dealprice"); ok {
+		return buyBoxOffer(price), nil
 	}
 
 	if price, ok := parse("#style_name_0_price"); ok {
-		return price, nil
+		return buyBoxOffer(price), nil
 	}
 
 	if price, ok := parse("#olp-upd-new > span > a > span.a-size-base.a-color-price"); ok {
-		return price, nil
+		return otherSellersOffer(price, "#olp-upd-new"), nil
 	}
 
 	if price, ok := parse("#olp-upd-new-used"); ok {
-		return price, nil
+		return otherSellersOffer(price, "#olp-upd-new-used"), nil
 	}
 
 	if price, ok := parse("#olp-upd-used"); ok {
-		return price, nil
+		return otherSellersOffer(price, "#olp-upd-used"), nil
 	}
 
 	// The easy scrapping parsing didn't work, time to bring the big guns
-	price, err := navigateAndParseBestBuyingOption(link)
+	offer, err := navigateAndParseBestBuyingOption(ctx, c, doc, link)
 	if err == nil {
-		return price, nil
+		return offer, nil
 	}
 
 	errs = append(errs, err)
 	// Handling more price parsing options will give us more product options
-	return 0, toErr(errs)
+	return Offer{}, &ParseError{
+		URL:       link,
+		Field:     "price",
+		Selectors: selectors,
+		Err:       toErr(errs),
+	}
 }
 
 func Filter(name string, prods []Product) []Product {
@@ -129,131 +218,94 @@ func Filter(name string, prods []Product) []Product {
 func SortByPrice(prods []Product) {
 	// FIXME: move to product package
 	sort.Slice(prods, func(i, j int) bool {
-		return prods[i].Price < prods[j].Price
+		return prods[i].Price.Amount < prods[j].Price.Amount
 	})
 }
 
-func navigateAndParseBestBuyingOption(link string) (float64, error) {
+func navigateAndParseBestBuyingOption(ctx context.Context, c *fetch.Client, doc *goquery.Document, link string) (Offer, error) {
 	linkUrl, err := url.Parse(link)
 	if err != nil {
-		return 0, err
+		return Offer{}, err
 	}
 
-	productId := filepath.Base(linkUrl.Path)
-
-	entrypointURL := fmt.Sprintf("https://%s/gp/offer-listing/%s", linkUrl.Hostname(), productId)
-
-	responseBody, err := doRequest(entrypointURL)
+	productId, err := parseASIN(doc, link)
 	if err != nil {
-		return 0, err
+		return Offer{}, err
 	}
 
-	doc, err := goquery.NewDocumentFromReader(responseBody)
-	if err != nil {
-		return 0, err
-	}
+	entrypointURL := fmt.Sprintf("%s://%s/gp/offer-listing/%s", linkUrl.Scheme, linkUrl.Host, productId)
 
-	cssSelector := "#olpOfferList > div > div > div.a-row.a-spacing-mini.olpOffer > div.a-column.a-span2.olpPriceColumn > span"
-	moneyText := doc.Find(cssSelector).Text()
-	if moneyText == "" {
-		return 0, fmt.Errorf("selector %q selected nothing", cssSelector)
-	}
-	price, err := parseMoney(moneyText)
+	offers, err := getOffers(ctx, c, entrypointURL)
 	if err != nil {
-		return 0, err
+		return Offer{}, err
 	}
-	return price, nil
 
+	best, _ := BestOffer(offers)
+	return offers[best], nil
 }
 
-func parseProduct(html io.Reader, url string) (Product, error) {
+const nameSelector = "#productTitle"
+
+func parseProduct(ctx context.Context, c *fetch.Client, html io.Reader, link string) (Product, error) {
 	doc, err := goquery.NewDocumentFromReader(html)
 	if err != nil {
 
 		return Product{}, err
 	}
 
-	name := strings.TrimSpace(doc.Find("#productTitle").Text())
+	name := strings.TrimSpace(doc.Find(nameSelector).Text())
 	if name == "" {
-		return Product{}, errors.New("cant parse product name")
+		return Product{}, &ParseError{
+			URL:       link,
+			Field:     "name",
+			Selectors: []string{nameSelector},
+		}
 	}
 
-	price, err := ParsePrice(doc, url)
+	asin, err := parseASIN(doc, link)
 	if err != nil {
-		return Product{}, fmt.Errorf("cant parse product price:\n%v", err)
-	}
-
-	return Product{
-		URL:   url,
-		Name:  name,
-		Price: price,
-	}, nil
-}
-
-func parseMoney(s string) (float64, error) {
-	// Yeah using float for money is not great...
-	s = strings.TrimSpace(s)
-	v, err := strconv.ParseFloat(s, 64)
-	if err == nil {
-		return v, nil
+		return Product{}, &ParseError{
+			URL:       link,
+			Field:     "ASIN",
+			Selectors: []string{asinSelector, canonicalSelector},
+			Err:       err,
+		}
 	}
 
-	re := regexp.MustCompile(`[,.0-9]+`)
-	s = re.FindString(s)
-	v, err = strconv.ParseFloat(s, 64)
-	if err == nil {
-		return v, nil
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return Product{}, err
 	}
 
-	moneyText := strings.Replace(s, ",", "", -1)
-	moneyText = strings.Replace(moneyText, ".", "", -1)
-	v, err = strconv.ParseFloat(moneyText, 64)
-	if err != nil {
-		return 0, fmt.Errorf("can't parse %q : %v", s, err)
+	prod := Product{
+		URL:          link,
+		ASIN:         asin,
+		CanonicalURL: canonicalURL(linkURL.Host, asin),
+		Name:         name,
 	}
-	// After removing ',' and '.' need to divide by 100 to get cents back
-	v = v / 100
-	return v, nil
-}
+	parseDetails(doc, &prod)
 
-func doRequest(link string) (io.Reader, error) {
-	req, err := http.NewRequest(http.MethodGet, link, nil)
+	offer, err := ParseBuyingOfferContext(ctx, c, doc, link)
 	if err != nil {
-		return nil, err
+		return prod, err
 	}
-	header.Add(req)
-
-	c := &http.Client{Timeout: 30 * time.Second}
-
-	const throttleTime = time.Second
 
-	time.Sleep(throttleTime)
+	prod.Price = offer.Price
+	prod.Shipping = offer.Shipping
+	prod.ImportFees = offer.ImportFees
+	return prod, nil
+}
 
-	res, err := c.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if res.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(res.Body)
-		res.Body.Close()
-		return nil, fmt.Errorf(
-			"url %q unexpected status %d; resp body:\n%s",
-			link,
-			res.StatusCode,
-			string(body),
-		)
+// parseASIN parses the ASIN from the link of the product page,
+// falling back to the page itself for links without an ASIN.
+func parseASIN(doc *goquery.Document, link string) (string, error) {
+	if asin, err := ParseASIN(link); err == nil {
+		return asin, nil
 	}
-	return res.Body, nil
+	return parsePageASIN(doc)
 }
-func toErr(errs []error) error {
-	// FIXME: Copied from search
-	if len(errs) == 0 {
-		return nil
-	}
 
-	errmsgs := make([]string, len(errs))
-	for i, err := range errs {
-		errmsgs[i] = err.Error()
-	}
-	return errors.New(strings.Join(errmsgs, "\n"))
+func doRequest(ctx context.Context, c *fetch.Client, link string) (io.Reader, error) {
+	// Throttling is done by the client rate limiter
+	return c.GetContext(ctx, link)
 }
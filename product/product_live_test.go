@@ -0,0 +1,35 @@
+//go:build live
+// +build live
+
+package product_test
+
+import (
+	"testing"
+
+	"github.com/katcipis/amazoner/product"
+)
+
+func TestProductGet(t *testing.T) {
+	// Talks with the real Amazon, run with: go test -tags live ./...
+	// The offline tests on product_test.go are more reliable and faster.
+	urls := []string{
+		"https://www.amazon.com/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWW",
+		"https://www.amazon.com/MSI-RTX-2070-Super-Architecture/dp/B0856BVRFL",
+		"https://www.amazon.com/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWW",
+	}
+
+	for _, url := range urls {
+		t.Run(url, func(t *testing.T) {
+			p, err := product.Get(nil, url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Name == "" {
+				t.Error("missing name on product")
+			}
+			if p.Price.Amount <= 0 {
+				t.Error("missing price on product")
+			}
+		})
+	}
+}
@@ -1,87 +1,507 @@
 package product_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/katcipis/amazoner/amazontest"
+	"github.com/katcipis/amazoner/availability"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
 	"github.com/katcipis/amazoner/product"
 )
 
-func TestProductGet(t *testing.T) {
-	// Usually isolated parsing tests would be more reliable and faster
-	// But right now corners are being cut :-)
-	urls := []string{
-		"https://www.amazon.com/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWW",
-		"https://www.amazon.com/MSI-RTX-2070-Super-Architecture/dp/B0856BVRFL",
-		"https://www.amazon.com/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWW",
+// client has no rate limit or retries, so tests run fast
+var client = &fetch.Client{
+	Limiter: fetch.NewLimiter(0, 1),
+	Retry:   &fetch.Retry{Attempts: 1},
+}
+
+func TestGet(t *testing.T) {
+	type Test struct {
+		name      string
+		path      string
+		page      string
+		wantName  string
+		wantPrice money.Money
+	}
+
+	tests := []Test{
+		{
+			name:      "PriceInsideBuybox",
+			path:      "/MSI-RTX-2070-Super-Architecture/dp/B0856BVRFL",
+			page:      amazontest.Fixture("product_price_inside_buybox.html"),
+			wantName:  "MSI Gaming GeForce RTX 2070 Super 8GB GDRR6 256-Bit HDMI/DP G-SYNC Turing Architecture Overclocked Graphics Card (RTX 2070 Super Ventus GP OC)",
+			wantPrice: money.Money{Amount: 93999, Currency: "USD"},
+		},
+		{
+			name:      "PriceBlockOurPrice",
+			path:      "/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4",
+			page:      amazontest.Fixture("product_priceblock_ourprice.html"),
+			wantName:  "MSI Gaming GeForce RTX 3070 8GB GDRR6 256-Bit HDMI/DP TORX Fan 3.0 Ampere Architecture OC Graphics Card (RTX 3070 Ventus 3X OC)",
+			wantPrice: money.Money{Amount: 123456, Currency: "USD"},
+		},
+		{
+			name:      "StyleNamePrice",
+			path:      "/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWW",
+			page:      amazontest.Fixture("product_style_name_price.html"),
+			wantName:  "MSI Gaming GeForce GTX 1660 Super 192-bit HDMI/DP 6GB GDRR6 HDCP Support DirectX 12 Dual Fan VR Ready OC Graphics Card (GTX 1660 Super Ventus XS OC)",
+			wantPrice: money.Money{Amount: 49999, Currency: "USD"},
+		},
+		{
+			name:      "OtherSellersNew",
+			path:      "/MSI-GeForce-RTX-2060-Architecture/dp/B07MQ36Z6L",
+			page:      amazontest.Fixture("product_olp_upd_new.html"),
+			wantName:  "MSI Gaming GeForce RTX 2060 6GB GDRR6 192-bit HDMI/DP Ray Tracing Turing Architecture VR Ready Graphics Card (RTX 2060 Ventus XS 6G OC)",
+			wantPrice: money.Money{Amount: 82900, Currency: "USD"},
+		},
+		{
+			name:      "OtherSellersNewAndUsed",
+			path:      "/MSI-GeForce-RTX-2060-Architecture/dp/B07MQ36Z6L",
+			page:      amazontest.Fixture("product_olp_upd_new_used.html"),
+			wantName:  "MSI Gaming GeForce RTX 2060 6GB GDRR6 192-bit HDMI/DP Ray Tracing Turing Architecture VR Ready Graphics Card (RTX 2060 Ventus XS 6G OC)",
+			wantPrice: money.Money{Amount: 61250, Currency: "USD"},
+		},
+		{
+			name:      "OtherSellersUsed",
+			path:      "/MSI-GeForce-RTX-2060-Architecture/dp/B07MQ36Z6L",
+			page:      amazontest.Fixture("product_olp_upd_used.html"),
+			wantName:  "MSI Gaming GeForce RTX 2060 6GB GDRR6 192-bit HDMI/DP Ray Tracing Turing Architecture VR Ready Graphics Card (RTX 2060 Ventus XS 6G OC)",
+			wantPrice: money.Money{Amount: 54000, Currency: "USD"},
+		},
+		{
+			name:      "OfferListing",
+			path:      "/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWX",
+			page:      amazontest.Fixture("product_available_from_sellers.html"),
+			wantName:  "MSI Gaming GeForce GTX 1660 Super 192-bit HDMI/DP 6GB GDRR6 HDCP Support DirectX 12 Dual Fan VR Ready OC Graphics Card (GTX 1660 Super Ventus XS OC)",
+			wantPrice: money.Money{Amount: 35999, Currency: "USD"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := amazontest.NewServer(t)
+			server.Handle(test.path, amazontest.Page{File: test.page})
+			server.Handle("/gp/offer-listing/B07YXPVBWX", amazontest.Page{
+				File: amazontest.Fixture("offer_listing.html"),
+			})
+
+			link := server.URL + test.path
+			p, err := product.Get(client, link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Name != test.wantName {
+				t.Errorf("got name %q; want %q", p.Name, test.wantName)
+			}
+			if p.Price != test.wantPrice {
+				t.Errorf("got price %v; want %v", p.Price, test.wantPrice)
+			}
+			if p.URL != link {
+				t.Errorf("got URL %q; want %q", p.URL, link)
+			}
+
+			wantASIN := test.path[strings.LastIndex(test.path, "/")+1:]
+			if p.ASIN != wantASIN {
+				t.Errorf("got ASIN %q; want %q", p.ASIN, wantASIN)
+			}
+			if want := "https://" + server.Domain() + "/dp/" + wantASIN; p.CanonicalURL != want {
+				t.Errorf("got canonical URL %q; want %q", p.CanonicalURL, want)
+			}
+		})
+	}
+}
+
+func TestGetFailures(t *testing.T) {
+	type Test struct {
+		name    string
+		page    amazontest.Page
+		wantErr string
+		wantIs  error
+	}
+
+	tests := []Test{
+		{
+			name:    "NoTitle",
+			page:    amazontest.Page{File: amazontest.Fixture("product_no_title.html")},
+			wantErr: "cant parse product name",
+			wantIs:  product.ErrParse,
+		},
+		{
+			name:    "Captcha",
+			page:    amazontest.Page{File: amazontest.Fixture("captcha.html")},
+			wantErr: "captcha challenge",
+			wantIs:  fetch.ErrCaptcha,
+		},
+		{
+			name:    "NotFound",
+			page:    amazontest.Page{File: amazontest.Fixture("product_no_title.html"), Status: 404},
+			wantErr: "unexpected status 404",
+			wantIs:  fetch.ErrNotFound,
+		},
+		{
+			name:    "RateLimited",
+			page:    amazontest.Page{File: amazontest.Fixture("product_no_title.html"), Status: 503},
+			wantErr: "unexpected status 503",
+			wantIs:  fetch.ErrRateLimited,
+		},
+		{
+			name:    "OfferListingUnavailable",
+			page:    amazontest.Page{File: amazontest.Fixture("product_available_from_sellers.html")},
+			wantErr: "cant parse product price",
+			wantIs:  product.ErrParse,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			const path = "/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWX"
+
+			server := amazontest.NewServer(t)
+			server.Handle(path, test.page)
+
+			p, err := product.Get(client, server.URL+path)
+			if err == nil {
+				t.Fatalf("want error, got product: %+v", p)
+			}
+			if !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("got error %q; want it to contain %q", err, test.wantErr)
+			}
+			if !errors.Is(err, test.wantIs) {
+				t.Fatalf("got error %v; want %v", err, test.wantIs)
+			}
+		})
+	}
+}
+
+func TestGetPriceParseError(t *testing.T) {
+	const path = "/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWX"
+
+	server := amazontest.NewServer(t)
+	server.Handle(path, amazontest.Page{File: amazontest.Fixture("product_available_from_sellers.html")})
+
+	_, err := product.Get(client, server.URL+path)
+
+	var parseErr *product.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got error %v; want %T", err, parseErr)
+	}
+	if parseErr.Field != "price" {
+		t.Errorf("got field %q; want %q", parseErr.Field, "price")
+	}
+	if parseErr.URL != server.URL+path {
+		t.Errorf("got URL %q; want %q", parseErr.URL, server.URL+path)
+	}
+	if len(parseErr.Selectors) == 0 || parseErr.Selectors[0] != "#price_inside_buybox" {
+		t.Errorf("got selectors %v; want the tried selectors", parseErr.Selectors)
+	}
+	// The offer listing page is not found, its error is kept
+	if !errors.Is(err, fetch.ErrNotFound) {
+		t.Errorf("got error %v; want %v", err, fetch.ErrNotFound)
+	}
+}
+
+func TestGetOutOfStockWithoutPrice(t *testing.T) {
+	const path = "/dp/B0856BVRFL"
+
+	server := amazontest.NewServer(t)
+	server.Handle(path, amazontest.Page{File: amazontest.Fixture("product_out_of_stock.html")})
+
+	p, err := product.Get(client, server.URL+path)
+	if !errors.Is(err, product.ErrParse) {
+		t.Fatalf("got error %v; want %v", err, product.ErrParse)
+	}
+
+	// The product is returned without price, so its availability is known
+	if p.ASIN != "B0856BVRFL" || p.Name == "" {
+		t.Errorf("got product %+v; want its ASIN and name", p)
+	}
+	if !p.Price.IsZero() {
+		t.Errorf("got price %v; want none", p.Price)
+	}
+	if p.InStock() {
+		t.Errorf("got in stock with availability %q; want out of stock", p.Availability)
 	}
+}
 
-	for _, url := range urls {
-		t.Run(url, func(t *testing.T) {
-			p, err := product.Get(url)
+func TestGetDetails(t *testing.T) {
+	type Test struct {
+		name string
+		path string
+		page string
+		want product.Product
+	}
+
+	tests := []Test{
+		{
+			name: "AllDetails",
+			path: "/ASUS-Graphics-DisplayPort-Axial-tech-2-9-Slot/dp/B08KY322TH",
+			page: amazontest.Fixture("product_deal.html"),
+			want: product.Product{
+				Price:   money.Money{Amount: 54999, Currency: "USD"},
+				Rating:  4.6,
+				Reviews: 2317,
+				Brand:   "ASUS",
+				Images: []string{
+					"https://images-na.ssl-images-amazon.com/images/I/81XdGsSH3fL._AC_SL1500_.jpg",
+					"https://images-na.ssl-images-amazon.com/images/I/81XdGsSH3fL._AC_SX679_.jpg",
+					"https://images-na.ssl-images-amazon.com/images/I/81XdGsSH3fL._AC_SX522_.jpg",
+					"https://images-na.ssl-images-amazon.com/images/I/81XdGsSH3fL._AC_SX355_.jpg",
+				},
+				Availability: "Only 3 left in stock - order soon.",
+				SoldBy:       "Amazon.com",
+				ShipsFrom:    "Amazon.com",
+				Prime:        true,
+				ListPrice:    money.Money{Amount: 59999, Currency: "USD"},
+				DealPrice:    money.Money{Amount: 54999, Currency: "USD"},
+				Features: []string{
+					"NVIDIA Ampere Streaming Multiprocessors: The all-new Ampere SM brings 2X the FP32 throughput.",
+					"2nd Generation RT Cores: Experience 2X the throughput of 1st gen RT Cores.",
+					"Military-grade Certification: TUF components are tested for durability.",
+				},
+			},
+		},
+		{
+			name: "MerchantInfo",
+			path: "/MSI-RTX-2070-Super-Architecture/dp/B0856BVRFL",
+			page: amazontest.Fixture("product_price_inside_buybox.html"),
+			want: product.Product{
+				Price:        money.Money{Amount: 93999, Currency: "USD"},
+				Brand:        "MSI",
+				Availability: "In Stock.",
+				SoldBy:       "GPU Outlet",
+				ShipsFrom:    "Amazon",
+			},
+		},
+		{
+			name: "MissingDetails",
+			path: "/MSI-GeForce-RTX-2060-Architecture/dp/B07MQ36Z6L",
+			page: amazontest.Fixture("product_olp_upd_used.html"),
+			want: product.Product{
+				Price:        money.Money{Amount: 54000, Currency: "USD"},
+				Brand:        "MSI",
+				Availability: "Currently unavailable. We don't know when or if this item will be back in stock.",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := amazontest.NewServer(t)
+			server.Handle(test.path, amazontest.Page{File: test.page})
+
+			p, err := product.Get(client, server.URL+test.path)
 			if err != nil {
 				t.Fatal(err)
 			}
-			if p.Name == "" {
-				t.Error("missing name on product")
+
+			// Identity is checked on TestGet
+			p.URL, p.ASIN, p.CanonicalURL, p.Name = "", "", "", ""
+
+			if !reflect.DeepEqual(p, test.want) {
+				t.Fatalf("got product:\n%+v\nwant:\n%+v", p, test.want)
 			}
-			if p.Price <= 0 {
-				t.Error("missing price on product")
+		})
+	}
+}
+
+func TestStock(t *testing.T) {
+	type Test struct {
+		name        string
+		prod        product.Product
+		wantStatus  availability.Status
+		wantInStock bool
+	}
+
+	tests := []Test{
+		{
+			name:        "LowStock",
+			prod:        product.Product{URL: "https://www.amazon.nl/dp/B08KWLMZV4", Availability: "Nog maar 2 op voorraad."},
+			wantStatus:  availability.LowStock,
+			wantInStock: true,
+		},
+		{
+			name:        "OutOfStock",
+			prod:        product.Product{CanonicalURL: "https://www.amazon.de/dp/B08KWLMZV4", Availability: "Derzeit nicht verfügbar."},
+			wantStatus:  availability.OutOfStock,
+			wantInStock: false,
+		},
+		{
+			name:        "Unknown",
+			prod:        product.Product{URL: "https://www.amazon.com/dp/B08KWLMZV4"},
+			wantStatus:  availability.Unknown,
+			wantInStock: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.prod.Stock().Status; got != test.wantStatus {
+				t.Errorf("got status %q; want %q", got, test.wantStatus)
+			}
+			if got := test.prod.InStock(); got != test.wantInStock {
+				t.Errorf("got in stock %t; want %t", got, test.wantInStock)
 			}
 		})
 	}
 }
 
+func TestGetProductsCancelled(t *testing.T) {
+	const path = "/MSI-RTX-2070-Super-Architecture/dp/B0856BVRFL"
+
+	server := amazontest.NewServer(t)
+	server.Handle(path, amazontest.Page{File: amazontest.Fixture("product_price_inside_buybox.html")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	urls := []string{server.URL + path, server.URL + path}
+	prods, err := product.GetProductsContext(ctx, client, urls)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err %v; want %v", err, context.Canceled)
+	}
+	if len(prods) != 0 {
+		t.Fatalf("got products %v; want none", prods)
+	}
+	if got := server.Requests(path); got != 0 {
+		t.Fatalf("got %d requests; want none", got)
+	}
+}
+
+func TestFetcher(t *testing.T) {
+	paths := []string{
+		"/MSI-RTX-2070-Super-Architecture/dp/B0856BVRFL",
+		"/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4",
+		"/missing/dp/B000000000",
+		"/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWW",
+	}
+
+	server := amazontest.NewServer(t)
+	server.Handle(paths[0], amazontest.Page{File: amazontest.Fixture("product_price_inside_buybox.html")})
+	server.Handle(paths[1], amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	server.Handle(paths[3], amazontest.Page{File: amazontest.Fixture("product_style_name_price.html")})
+
+	urls := make([]string, len(paths))
+	for i, path := range paths {
+		urls[i] = server.URL + path
+	}
+
+	fetcher := &product.Fetcher{Client: client, Workers: 2}
+	results := fetcher.FetchAll(context.Background(), urls)
+
+	if len(results) != len(urls) {
+		t.Fatalf("got %d results; want %d", len(results), len(urls))
+	}
+	for i, res := range results {
+		if res.URL != urls[i] {
+			t.Errorf("result %d got URL %q; want %q", i, res.URL, urls[i])
+		}
+		if i == 2 {
+			if res.Err == nil {
+				t.Errorf("result %d want error, got %+v", i, res.Product)
+			}
+			continue
+		}
+		if res.Err != nil {
+			t.Errorf("result %d unexpected error: %v", i, res.Err)
+		}
+		if res.Product.URL != urls[i] {
+			t.Errorf("result %d got product URL %q; want %q", i, res.Product.URL, urls[i])
+		}
+	}
+
+	prods, err := fetcher.Fetch(context.Background(), urls)
+	if err == nil {
+		t.Error("want error for missing product")
+	}
+	if len(prods) != 3 {
+		t.Errorf("got %d products; want partial result of 3", len(prods))
+	}
+	if !errors.Is(err, fetch.ErrNotFound) {
+		t.Errorf("got err %v; want %v", err, fetch.ErrNotFound)
+	}
+
+	var errs product.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got err %T; want %T", err, errs)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors; want 1: %v", len(errs), errs)
+	}
+
+	var urlErr *product.URLError
+	if !errors.As(err, &urlErr) {
+		t.Fatalf("got err %T; want %T", err, urlErr)
+	}
+	if urlErr.URL != urls[2] {
+		t.Errorf("got error of URL %q; want %q", urlErr.URL, urls[2])
+	}
+}
+
 func TestFilter(t *testing.T) {
 	searchResults := []product.Product{
 		{
 			Name:  "MSI GeForce RTX 3070 Ventus 3X OC Gaming Video Card, 8GB GDDR6, PCIe 4.0, 8K, VR Ready, Ray Tracing, 1x HDMI 2.1, 3X DisplayPort 1.4, Triple Fans, HDCP, Mytrix HDMI 2.1 8K Cable",
-			Price: 939.99,
+			Price: money.Money{Amount: 93999, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-RTX-3070-HDMI-DisplayPort/dp/B08MVFMN35",
 		},
 		{
 			Name:  "MSI GeForce RTX 3070 Ventus 3X OC Gaming Video Card, 8GB GDDR6, PCIe 4.0, 8K, VR Ready, Ray Tracing, 1x HDMI 2.1, 3X DisplayPort 1.4, Triple Fans, HDCP, Battlefield V",
-			Price: 939.99,
+			Price: money.Money{Amount: 93999, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-RTX-3070-DisplayPort-Battlefield/dp/B08MVHD9Z9",
 		},
 		{
 			Name:  "CyberpowerPC Gamer Xtreme VR Gaming PC, Intel i5-10400F 2.9GHz, GeForce GTX 1660 Super 6GB, 8GB DDR4, 500GB NVMe SSD, WiFi Ready & Win 10 Home (GXiVR8060A10)",
-			Price: 799.99,
+			Price: money.Money{Amount: 79999, Currency: "USD"},
 			URL:   "https://www.amazon.com/CyberpowerPC-Xtreme-i5-10400F-GeForce-GXiVR8060A10/dp/B08FBK2DK5",
 		},
 		{
 			Name:  "MSI Gaming GeForce RTX 3070 8GB GDRR6 256-Bit HDMI/DP Tri-Frozr 2 TORX Fan 4.0 Ampere Architecture RGB OC Graphics Card (RTX 3070 Gaming X Trio)",
-			Price: 959,
+			Price: money.Money{Amount: 95900, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-GeForce-RTX-3070-Architecture/dp/B08KWN2LZG",
 		},
 		{
 			Name:  "MSI Gaming GeForce RTX 3070 8GB GDRR6 256-Bit HDMI/DP TORX Fan 3.0 Ampere Architecture OC Graphics Card (RTX 3070 Ventus 3X OC)",
-			Price: 909.99,
+			Price: money.Money{Amount: 90999, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4",
 		},
 		{
 			Name:  "PNY GeForce RTX 3070 8GB XLR8 Gaming Revel Epic-X RGB Triple Fan Graphics Card",
-			Price: 949.99,
+			Price: money.Money{Amount: 94999, Currency: "USD"},
 			URL:   "https://www.amazon.com/PNY-GeForce-Gaming-Epic-X-Graphics/dp/B08HBJB7YD",
 		},
 		{
 			Name:  "ARESGAME 750W Power Supply Semi Modular 80+ Bronze PSU (AGV750)",
-			Price: 79.99,
+			Price: money.Money{Amount: 7999, Currency: "USD"},
 			URL:   "https://www.amazon.com/ARESGAME-Supply-Modular-Bronze-AGV750/dp/B08JM12SQ5",
 		},
 		{
 			Name:  "MSI GeForce RTX 3070 Ventus 3X OC Gaming Video Card, 8GB GDDR6, PCIe 4.0, 8K, VR Ready, Ray Tracing, 1x HDMI 2.1, 3X DisplayPort 1.4, Triple Fans, HDCP, Mytrix HDMI 2.1 8K Cable, Battlefield V",
-			Price: 939.99,
+			Price: money.Money{Amount: 93999, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-RTX-3070-DisplayPort-Battlefield/dp/B08MVH3QJF",
 		},
 		{
 			Name:  "Beelink U57 Mini PC with Intel Core i5-5257u Processor(up to 3.10 GHz)&Windows 10 Pro,8G DDR3L/256G SSD High Performance Business Mini Computer,2.4G/5G Dual WiFi,BT4.2,Dual HDMI Ports",
-			Price: 379,
+			Price: money.Money{Amount: 37900, Currency: "USD"},
 			URL:   "https://www.amazon.com/Beelink-U57-Processor-256G-Performance/dp/B0879KKTCB",
 		},
 		{
 			Name:  "EVGA 08G-P5-3767-KR GeForce RTX 3070 FTW3 Ultra Gaming, 8GB GDDR6, iCX3 Technology, ARGB LED, Metal Backplate",
-			Price: 999.99,
+			Price: money.Money{Amount: 99999, Currency: "USD"},
 			URL:   "https://www.amazon.com/EVGA-08G-P5-3767-KR-GeForce-Technology-Backplate/dp/B08L8L9TCZ",
 		},
 	}
@@ -123,37 +543,37 @@ func TestSortByPrice(t *testing.T) {
 	searchResults := []product.Product{
 		{
 			Name:  "MSI GeForce RTX 3070 Ventus 3X OC Gaming Video Card, 8GB GDDR6, PCIe 4.0, 8K, VR Ready, Ray Tracing, 1x HDMI 2.1, 3X DisplayPort 1.4, Triple Fans, HDCP, Mytrix HDMI 2.1 8K Cable",
-			Price: 939.99,
+			Price: money.Money{Amount: 93999, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-RTX-3070-HDMI-DisplayPort/dp/B08MVFMN35",
 		},
 		{
 			Name:  "MSI GeForce RTX 3070 Ventus 3X OC Gaming Video Card, 8GB GDDR6, PCIe 4.0, 8K, VR Ready, Ray Tracing, 1x HDMI 2.1, 3X DisplayPort 1.4, Triple Fans, HDCP, Battlefield V",
-			Price: 939.99,
+			Price: money.Money{Amount: 93999, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-RTX-3070-DisplayPort-Battlefield/dp/B08MVHD9Z9",
 		},
 		{
 			Name:  "MSI Gaming GeForce RTX 3070 8GB GDRR6 256-Bit HDMI/DP Tri-Frozr 2 TORX Fan 4.0 Ampere Architecture RGB OC Graphics Card (RTX 3070 Gaming X Trio)",
-			Price: 959,
+			Price: money.Money{Amount: 95900, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-GeForce-RTX-3070-Architecture/dp/B08KWN2LZG",
 		},
 		{
 			Name:  "MSI Gaming GeForce RTX 3070 8GB GDRR6 256-Bit HDMI/DP TORX Fan 3.0 Ampere Architecture OC Graphics Card (RTX 3070 Ventus 3X OC)",
-			Price: 909.99,
+			Price: money.Money{Amount: 90999, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4",
 		},
 		{
 			Name:  "PNY GeForce RTX 3070 8GB XLR8 Gaming Revel Epic-X RGB Triple Fan Graphics Card",
-			Price: 949.99,
+			Price: money.Money{Amount: 94999, Currency: "USD"},
 			URL:   "https://www.amazon.com/PNY-GeForce-Gaming-Epic-X-Graphics/dp/B08HBJB7YD",
 		},
 		{
 			Name:  "MSI GeForce RTX 3070 Ventus 3X OC Gaming Video Card, 8GB GDDR6, PCIe 4.0, 8K, VR Ready, Ray Tracing, 1x HDMI 2.1, 3X DisplayPort 1.4, Triple Fans, HDCP, Mytrix HDMI 2.1 8K Cable, Battlefield V",
-			Price: 939.99,
+			Price: money.Money{Amount: 93999, Currency: "USD"},
 			URL:   "https://www.amazon.com/MSI-RTX-3070-DisplayPort-Battlefield/dp/B08MVH3QJF",
 		},
 		{
 			Name:  "EVGA 08G-P5-3767-KR GeForce RTX 3070 FTW3 Ultra Gaming, 8GB GDDR6, iCX3 Technology, ARGB LED, Metal Backplate",
-			Price: 999.99,
+			Price: money.Money{Amount: 99999, Currency: "USD"},
 			URL:   "https://www.amazon.com/EVGA-08G-P5-3767-KR-GeForce-Technology-Backplate/dp/B08L8L9TCZ",
 		},
 	}
@@ -0,0 +1,189 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+)
+
+// Variant is a variation of a product, like another size,
+// color or style of the same product.
+type Variant struct {
+	ASIN string
+	// URL is the canonical URL of the variant.
+	URL string
+	// Dimensions maps the name of each dimension of the variation,
+	// like "Size" or "Style", to the value of the variant, like "12GB".
+	Dimensions map[string]string
+	// Price is the price shown on the variation selector, it is zero
+	// when it is not shown, like for variants that differ on more
+	// than one dimension from the selected variant. Get the variant
+	// URL for its full details.
+	Price money.Money
+	// Selected is true for the variant of the given product page.
+	Selected bool
+}
+
+// Variants gets all the variants of the product on the given link,
+// the product itself included. Products that have no variations
+// have no variants. If the client is nil a default client is used.
+func Variants(c *fetch.Client, link string) ([]Variant, error) {
+	return VariantsContext(context.Background(), c, link)
+}
+
+// VariantsContext is like Variants but with a context that can cancel the request.
+func VariantsContext(ctx context.Context, c *fetch.Client, link string) ([]Variant, error) {
+	responseBody, err := doRequest(ctx, c, link)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := goquery.NewDocumentFromReader(responseBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(doc.Find(nameSelector).Text()) == "" {
+		return nil, &ParseError{
+			URL:       link,
+			Field:     "name",
+			Selectors: []string{nameSelector},
+		}
+	}
+
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	return parseVariants(doc, linkURL.Host), nil
+}
+
+// parseVariants parses the variants from the twister, the variation
+// selector of the product page. All variants are listed on the twister
+// script data, while the twister HTML lists only the variants that
+// differ on a single dimension from the selected one, with their prices.
+func parseVariants(doc *goquery.Document, domain string) []Variant {
+	currency := domainCurrency(domain)
+	variants := []Variant{}
+	index := map[string]int{}
+
+	add := func(asin string, dimensions map[string]string) *Variant {
+		if i, ok := index[asin]; ok {
+			return &variants[i]
+		}
+		index[asin] = len(variants)
+		variants = append(variants, Variant{
+			ASIN:       asin,
+			URL:        canonicalURL(domain, asin),
+			Dimensions: dimensions,
+		})
+		return &variants[len(variants)-1]
+	}
+
+	names, values := parseTwisterData(doc)
+	for asin, vals := range values {
+		if len(vals) != len(names) || !isASIN(asin) {
+			continue
+		}
+		dimensions := map[string]string{}
+		for i, name := range names {
+			dimensions[name] = vals[i]
+		}
+		add(asin, dimensions)
+	}
+
+	selected := map[string]string{}
+	doc.Find(`#twister div[id^="variation_"]`).Each(func(i int, s *goquery.Selection) {
+		name := dimensionName(s)
+		selected[name] = strings.TrimSpace(s.Find(".selection").First().Text())
+	})
+
+	doc.Find(`#twister div[id^="variation_"]`).Each(func(i int, s *goquery.Selection) {
+		name := dimensionName(s)
+
+		s.Find("li[data-defaultasin]").Each(func(i int, li *goquery.Selection) {
+			asin, _ := li.Attr("data-defaultasin")
+			if !isASIN(asin) {
+				return
+			}
+
+			dimensions := map[string]string{}
+			for n, v := range selected {
+				dimensions[n] = v
+			}
+			dimensions[name] = strings.TrimSpace(li.Find(".twisterTextDiv").Text())
+
+			variant := add(asin, dimensions)
+			if price := parseMoney(li, ".twisterSwatchPrice", currency); !price.IsZero() {
+				variant.Price = price
+			}
+			if li.HasClass("swatchSelect") {
+				variant.Selected = true
+			}
+		})
+	})
+
+	sortVariants(variants)
+	return variants
+}
+
+// parseTwisterData parses the names of the dimensions and the
+// values of each ASIN for each dimension from the twister script.
+func parseTwisterData(doc *goquery.Document) ([]string, map[string][]string) {
+	var (
+		names  []string
+		values map[string][]string
+	)
+
+	doc.Find("#twister_feature_div script, #twister script").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		script := s.Text()
+		if !parseScriptValue(script, "dimensionsDisplay", &names) {
+			return true
+		}
+		return !parseScriptValue(script, "dimensionValuesDisplayData", &values)
+	})
+
+	return names, values
+}
+
+// parseScriptValue parses the JSON value of the given key on the script.
+func parseScriptValue(script, key string, v interface{}) bool {
+	start := strings.Index(script, `"`+key+`"`)
+	if start < 0 {
+		return false
+	}
+	script = script[start+len(key)+2:]
+
+	colon := strings.Index(script, ":")
+	if colon < 0 {
+		return false
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(script[colon+1:]))
+	return decoder.Decode(v) == nil
+}
+
+func dimensionName(s *goquery.Selection) string {
+	name := strings.TrimSpace(s.Find("label.a-form-label").First().Text())
+	name = strings.TrimSpace(strings.TrimSuffix(name, ":"))
+	if name != "" {
+		return name
+	}
+	// Fallback to the ID, like variation_size_name
+	id, _ := s.Attr("id")
+	return strings.TrimSuffix(strings.TrimPrefix(id, "variation_"), "_name")
+}
+
+// sortVariants sorts the variants by their ASIN, so the order
+// doesn't depend on the order of the data on the page.
+func sortVariants(variants []Variant) {
+	sort.Slice(variants, func(i, j int) bool {
+		return variants[i].ASIN < variants[j].ASIN
+	})
+}
@@ -0,0 +1,111 @@
+package product_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/katcipis/amazoner/amazontest"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+)
+
+func TestVariants(t *testing.T) {
+	const path = "/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWW"
+
+	type Test struct {
+		name string
+		page string
+		want []product.Variant
+	}
+
+	usd := func(amount int64) money.Money {
+		return money.Money{Amount: amount, Currency: "USD"}
+	}
+
+	tests := []Test{
+		{
+			name: "TwisterData",
+			page: amazontest.Fixture("product_variants.html"),
+			want: []product.Variant{
+				{
+					ASIN:       "B07YXPVBWW",
+					Dimensions: map[string]string{"Size": "6GB", "Style": "Ventus XS OC"},
+					Price:      usd(49999),
+					Selected:   true,
+				},
+				{
+					ASIN:       "B07ZHDZ1K6",
+					Dimensions: map[string]string{"Size": "6GB", "Style": "Gaming X"},
+					Price:      usd(52999),
+				},
+				{
+					ASIN:       "B08P2D1JZZ",
+					Dimensions: map[string]string{"Size": "12GB", "Style": "Ventus XS OC"},
+					Price:      usd(64900),
+				},
+				{
+					ASIN:       "B08P2GQ4KX",
+					Dimensions: map[string]string{"Size": "12GB", "Style": "Gaming X"},
+				},
+			},
+		},
+		{
+			name: "TwisterHTMLOnly",
+			page: amazontest.Fixture("product_style_name_price.html"),
+			want: []product.Variant{
+				{
+					ASIN:       "B07YXPVBWW",
+					Dimensions: map[string]string{"Style": "Ventus XS OC"},
+					Price:      usd(49999),
+					Selected:   true,
+				},
+				{
+					ASIN:       "B07ZHDZ1K6",
+					Dimensions: map[string]string{"Style": "Gaming X"},
+					Price:      usd(52999),
+				},
+			},
+		},
+		{
+			name: "NoVariants",
+			page: amazontest.Fixture("product_priceblock_ourprice.html"),
+			want: []product.Variant{},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := amazontest.NewServer(t)
+			server.Handle(path, amazontest.Page{File: test.page})
+
+			got, err := product.Variants(client, server.URL+path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := range test.want {
+				test.want[i].URL = "https://" + server.Domain() + "/dp/" + test.want[i].ASIN
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got variants:\n%+v\nwant:\n%+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVariantsFailures(t *testing.T) {
+	const path = "/MSI-Twin-Frozr-Architecture-Overclocked-Graphics/dp/B07YXPVBWW"
+
+	server := amazontest.NewServer(t)
+	server.Handle(path, amazontest.Page{File: amazontest.Fixture("captcha.html")})
+
+	if _, err := product.Variants(client, server.URL+path); err == nil {
+		t.Fatal("want error on captcha page")
+	}
+	if _, err := product.Variants(client, server.URL+"/missing/dp/B000000000"); err == nil {
+		t.Fatal("want error on missing page")
+	}
+}
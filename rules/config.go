@@ -0,0 +1,145 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/katcipis/amazoner/money"
+)
+
+// Config is the rules configuration, usually loaded from a JSON file
+// with LoadConfig. Durations are strings like "5m" or "168h" and
+// prices are strings like "$600" or "600.00 EUR".
+type Config struct {
+	Interval string       `json:"interval"`
+	Jitter   string       `json:"jitter"`
+	Rules    []RuleConfig `json:"rules"`
+}
+
+// RuleConfig is the configuration of a Rule.
+// The domain defaults to www.amazon.com.
+type RuleConfig struct {
+	Name           string `json:"name"`
+	Domain         string `json:"domain"`
+	ASIN           string `json:"asin"`
+	MaxLandedPrice string `json:"max_landed_price"`
+	Seller         string `json:"seller"`
+	InStock        bool   `json:"in_stock"`
+	MaxUnits       int    `json:"max_units"`
+	Period         string `json:"period"`
+}
+
+type Error string
+
+const ErrInvalidConfig Error = "invalid config"
+
+// LoadConfig loads the config from the JSON file on the given path.
+func LoadConfig(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+
+	return ParseConfig(file)
+}
+
+// ParseConfig parses the config from JSON.
+func ParseConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("%w : %v", ErrInvalidConfig, err)
+	}
+	return cfg, nil
+}
+
+// Engine creates an Engine with the config, validating it.
+// The ledger, buyer and other options can be set
+// on the returned Engine.
+func (cfg Config) Engine() (*Engine, error) {
+	e := &Engine{}
+
+	var err error
+	if e.Interval, err = parseDuration("interval", cfg.Interval); err != nil {
+		return nil, err
+	}
+	if e.Jitter, err = parseDuration("jitter", cfg.Jitter); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("%w : no rules", ErrInvalidConfig)
+	}
+
+	names := map[string]bool{}
+	for i, rc := range cfg.Rules {
+		rule, err := rc.rule()
+		if err != nil {
+			return nil, fmt.Errorf("%w : rule %d : %v", ErrInvalidConfig, i, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("%w : rule %d : duplicated name %q", ErrInvalidConfig, i, rule.Name)
+		}
+		names[rule.Name] = true
+		e.Rules = append(e.Rules, rule)
+	}
+
+	return e, nil
+}
+
+func (rc RuleConfig) rule() (Rule, error) {
+	rule := Rule{
+		Name:     rc.Name,
+		Domain:   rc.Domain,
+		ASIN:     rc.ASIN,
+		Seller:   rc.Seller,
+		InStock:  rc.InStock,
+		MaxUnits: rc.MaxUnits,
+	}
+
+	if rule.Name == "" {
+		return Rule{}, fmt.Errorf("rule has no name")
+	}
+	if rule.ASIN == "" {
+		return Rule{}, fmt.Errorf("rule %q has no asin", rule.Name)
+	}
+	if rule.Domain == "" {
+		rule.Domain = "www.amazon.com"
+	}
+	if rule.MaxUnits < 0 {
+		return Rule{}, fmt.Errorf("rule %q has negative max_units", rule.Name)
+	}
+
+	if rc.MaxLandedPrice == "" {
+		return Rule{}, fmt.Errorf("rule %q has no max_landed_price", rule.Name)
+	}
+	var err error
+	if rule.MaxLandedPrice, err = money.Parse(rc.MaxLandedPrice); err != nil {
+		return Rule{}, fmt.Errorf("rule %q max_landed_price : %v", rule.Name, err)
+	}
+
+	if rule.Period, err = parseDuration("period", rc.Period); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+func parseDuration(name, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w : %s : %v", ErrInvalidConfig, name, err)
+	}
+	return d, nil
+}
+
+func (e Error) Error() string {
+	return string(e)
+}
@@ -0,0 +1,146 @@
+package rules_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/rules"
+)
+
+func TestConfig(t *testing.T) {
+	const config = `{
+		"interval": "10m",
+		"jitter": "1m",
+		"rules": [
+			{
+				"name": "asus rtx 3070",
+				"asin": "B08KY322TH",
+				"max_landed_price": "600 USD",
+				"seller": "Amazon",
+				"in_stock": true,
+				"max_units": 1,
+				"period": "168h"
+			},
+			{
+				"name": "msi rtx 3070",
+				"domain": "www.amazon.de",
+				"asin": "B08KWLMZV4",
+				"max_landed_price": "650.00 EUR"
+			}
+		]
+	}`
+
+	cfg, err := rules.ParseConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := cfg.Engine()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Interval != 10*time.Minute || e.Jitter != time.Minute {
+		t.Errorf("got interval %v jitter %v; want 10m 1m", e.Interval, e.Jitter)
+	}
+
+	want := []rules.Rule{
+		{
+			Name:           "asus rtx 3070",
+			Domain:         "www.amazon.com",
+			ASIN:           "B08KY322TH",
+			MaxLandedPrice: money.Money{Amount: 60000, Currency: "USD"},
+			Seller:         "Amazon",
+			InStock:        true,
+			MaxUnits:       1,
+			Period:         168 * time.Hour,
+		},
+		{
+			Name:           "msi rtx 3070",
+			Domain:         "www.amazon.de",
+			ASIN:           "B08KWLMZV4",
+			MaxLandedPrice: money.Money{Amount: 65000, Currency: "EUR"},
+		},
+	}
+
+	if len(e.Rules) != len(want) {
+		t.Fatalf("got rules %+v; want %+v", e.Rules, want)
+	}
+	for i := range want {
+		if e.Rules[i] != want[i] {
+			t.Errorf("got rule %+v; want %+v", e.Rules[i], want[i])
+		}
+	}
+}
+
+func TestConfigFailures(t *testing.T) {
+	type Test struct {
+		name   string
+		config string
+	}
+
+	tests := []Test{
+		{
+			name:   "InvalidJSON",
+			config: `{"rules": [`,
+		},
+		{
+			name:   "UnknownField",
+			config: `{"rules": [{"name": "a", "asin": "B08KY322TH", "max_landed_price": "$600", "max_price": "$600"}]}`,
+		},
+		{
+			name:   "NoRules",
+			config: `{"rules": []}`,
+		},
+		{
+			name:   "NoName",
+			config: `{"rules": [{"asin": "B08KY322TH", "max_landed_price": "$600"}]}`,
+		},
+		{
+			name:   "NoASIN",
+			config: `{"rules": [{"name": "a", "max_landed_price": "$600"}]}`,
+		},
+		{
+			name:   "NoMaxLandedPrice",
+			config: `{"rules": [{"name": "a", "asin": "B08KY322TH"}]}`,
+		},
+		{
+			name:   "InvalidMaxLandedPrice",
+			config: `{"rules": [{"name": "a", "asin": "B08KY322TH", "max_landed_price": "cheap"}]}`,
+		},
+		{
+			name:   "NegativeMaxUnits",
+			config: `{"rules": [{"name": "a", "asin": "B08KY322TH", "max_landed_price": "$600", "max_units": -1}]}`,
+		},
+		{
+			name:   "InvalidPeriod",
+			config: `{"rules": [{"name": "a", "asin": "B08KY322TH", "max_landed_price": "$600", "period": "weekly"}]}`,
+		},
+		{
+			name: "DuplicatedName",
+			config: `{"rules": [
+				{"name": "a", "asin": "B08KY322TH", "max_landed_price": "$600"},
+				{"name": "a", "asin": "B08KWLMZV4", "max_landed_price": "$600"}
+			]}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := rules.ParseConfig(strings.NewReader(test.config))
+			if err == nil {
+				_, err = cfg.Engine()
+			}
+			if err == nil {
+				t.Fatal("want error")
+			}
+			if !errors.Is(err, rules.ErrInvalidConfig) {
+				t.Errorf("got error %v; want %v", err, rules.ErrInvalidConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/katcipis/amazoner/jsonl"
+	"github.com/katcipis/amazoner/money"
+)
+
+// Entry is a purchase made by a rule.
+type Entry struct {
+	// ID identifies the purchase, an entry replaces the
+	// entries added before it with the same ID.
+	ID     string      `json:"id,omitempty"`
+	Rule   string      `json:"rule"`
+	Domain string      `json:"domain"`
+	ASIN   string      `json:"asin"`
+	Time   time.Time   `json:"time"`
+	Price  money.Money `json:"price"`
+	// LandedPrice is the price plus shipping and import fees.
+	LandedPrice money.Money `json:"landed_price"`
+	// Status is the status of the purchase,
+	// entries without a status are Completed.
+	Status Status `json:"status,omitempty"`
+}
+
+// Status is the status of a purchase.
+type Status string
+
+const (
+	// Pending is a purchase being made. If the process dies while making
+	// it, it stays pending and counts as bought, since the order may have
+	// been placed.
+	Pending   Status = "pending"
+	Completed Status = "completed"
+	// Failed is a purchase that failed, it doesn't count as bought.
+	Failed Status = "failed"
+)
+
+// Ledger records the purchases on a file, one JSON line for each
+// purchase, so purchases made before a restart are not made again.
+// It is safe for concurrent use.
+type Ledger struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries []Entry
+}
+
+// OpenLedger opens the ledger on the given path, creating the file
+// if it doesn't exist. The ledger must be closed after use.
+func OpenLedger(path string) (*Ledger, error) {
+	entries, err := loadLedger(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := jsonl.OpenAppend(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ledger{
+		file:    file,
+		entries: entries,
+	}, nil
+}
+
+// Add adds the purchase to the ledger, persisting it on the file. If the
+// ledger has an entry with the same ID it is replaced, like to update
+// the status of a pending purchase.
+func (l *Ledger) Add(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing purchase ledger : %v", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("syncing purchase ledger : %v", err)
+	}
+
+	l.entries = addEntry(l.entries, entry)
+	return nil
+}
+
+// Count returns how many purchases the rule made since the given time,
+// including the pending ones.
+func (l *Ledger) Count(rule string, since time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := 0
+	for _, entry := range l.entries {
+		if entry.Rule == rule && entry.Status != Failed && !entry.Time.Before(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// Entries returns all the purchases, on the order they were added.
+func (l *Ledger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Entry{}, l.entries...)
+}
+
+// Close closes the ledger file.
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+func loadLedger(path string) ([]Entry, error) {
+	entries := []Entry{}
+
+	err := jsonl.Load(path, func(line []byte) error {
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		entries = addEntry(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading purchase ledger %q : %v", path, err)
+	}
+	return entries, nil
+}
+
+// addEntry adds the entry, replacing the entry with the same ID.
+func addEntry(entries []Entry, entry Entry) []Entry {
+	if entry.ID != "" {
+		for i := range entries {
+			if entries[i].ID == entry.ID {
+				entries[i] = entry
+				return entries
+			}
+		}
+	}
+	return append(entries, entry)
+}
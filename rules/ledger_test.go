@@ -0,0 +1,185 @@
+package rules_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/rules"
+)
+
+func TestLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	now := time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	entries := []rules.Entry{
+		{Rule: "a", Domain: "www.amazon.com", ASIN: "B08KY322TH", Time: now.Add(-48 * time.Hour), Price: money.Money{Amount: 54999, Currency: "USD"}},
+		{Rule: "b", Domain: "www.amazon.de", ASIN: "B08KWLMZV4", Time: now.Add(-time.Hour)},
+		{Rule: "a", Domain: "www.amazon.com", ASIN: "B08KY322TH", Time: now},
+	}
+
+	ledger, err := rules.OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		addEntry(t, ledger, entry)
+	}
+	if err := ledger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A partially written entry must be ignored
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"rule": "a", "asi`)
+	file.Close()
+
+	ledger, err = rules.OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ledger.Close()
+
+	if got := ledger.Entries(); !reflect.DeepEqual(got, entries) {
+		t.Errorf("got entries %+v; want %+v", got, entries)
+	}
+
+	type Test struct {
+		rule  string
+		since time.Time
+		want  int
+	}
+
+	tests := []Test{
+		{rule: "a", want: 2},
+		{rule: "a", since: now.Add(-24 * time.Hour), want: 1},
+		{rule: "a", since: now, want: 1},
+		{rule: "a", since: now.Add(time.Second), want: 0},
+		{rule: "b", want: 1},
+		{rule: "c", want: 0},
+	}
+
+	for _, test := range tests {
+		if got := ledger.Count(test.rule, test.since); got != test.want {
+			t.Errorf("rule %q since %v: got count %d; want %d", test.rule, test.since, got, test.want)
+		}
+	}
+}
+
+func TestLedgerAddAfterPartialEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	now := time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC)
+	before := rules.Entry{Rule: "a", Domain: "www.amazon.com", ASIN: "B08KY322TH", Time: now.Add(-time.Hour)}
+	after := rules.Entry{Rule: "a", Domain: "www.amazon.com", ASIN: "B08KY322TH", Time: now}
+
+	ledger, err := rules.OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addEntry(t, ledger, before)
+	if err := ledger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Like when the process was killed while writing an entry
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"rule": "a", "asi`)
+	file.Close()
+
+	ledger, err = rules.OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addEntry(t, ledger, after)
+	if err := ledger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The purchase made after the partial entry must not be lost with it
+	ledger, err = rules.OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ledger.Close()
+
+	if got, want := ledger.Entries(), []rules.Entry{before, after}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries %+v; want %+v", got, want)
+	}
+	if got := ledger.Count("a", time.Time{}); got != 2 {
+		t.Errorf("got count %d; want 2", got)
+	}
+}
+
+func TestLedgerStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	now := time.Now()
+
+	ledger, err := rules.OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addEntry(t, ledger, rules.Entry{ID: "a-1", Rule: "a", Time: now, Status: rules.Pending})
+	addEntry(t, ledger, rules.Entry{ID: "b-1", Rule: "b", Time: now, Status: rules.Pending})
+	addEntry(t, ledger, rules.Entry{ID: "c-1", Rule: "c", Time: now, Status: rules.Pending})
+	addEntry(t, ledger, rules.Entry{ID: "a-1", Rule: "a", Time: now, Status: rules.Completed})
+	addEntry(t, ledger, rules.Entry{ID: "b-1", Rule: "b", Time: now, Status: rules.Failed})
+	if err := ledger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger, err = rules.OpenLedger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ledger.Close()
+
+	type Test struct {
+		rule       string
+		wantStatus rules.Status
+		wantCount  int
+	}
+
+	tests := []Test{
+		{rule: "a", wantStatus: rules.Completed, wantCount: 1},
+		{rule: "b", wantStatus: rules.Failed, wantCount: 0},
+		// Pending purchases may have been made, so they count
+		{rule: "c", wantStatus: rules.Pending, wantCount: 1},
+	}
+
+	entries := ledger.Entries()
+	if len(entries) != len(tests) {
+		t.Fatalf("got entries %+v; want one for each purchase", entries)
+	}
+
+	for i, test := range tests {
+		if got := entries[i]; got.Rule != test.rule || got.Status != test.wantStatus {
+			t.Errorf("got entry %+v; want rule %q with status %q", got, test.rule, test.wantStatus)
+		}
+		if got := ledger.Count(test.rule, time.Time{}); got != test.wantCount {
+			t.Errorf("rule %q: got count %d; want %d", test.rule, got, test.wantCount)
+		}
+	}
+}
+
+func TestLedgerFailures(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "ledger"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ledger path is a file, so it can't be a directory
+	if _, err := rules.OpenLedger(filepath.Join(dir, "ledger", "ledger.jsonl")); err == nil {
+		t.Fatal("want error opening ledger on invalid path")
+	}
+}
@@ -0,0 +1,366 @@
+// Package rules buys products automatically when the conditions
+// of declarative rules are met, like a max landed price, the seller
+// and how many units were already bought.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/katcipis/amazoner/availability"
+	"github.com/katcipis/amazoner/buy"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+)
+
+// Rule buys one unit of a product when all its conditions are met.
+type Rule struct {
+	// Name identifies the rule on the ledger, so it must be unique.
+	Name string
+	// Domain and ASIN identify the product to buy.
+	Domain string
+	ASIN   string
+
+	// MaxLandedPrice is the max price plus shipping and import fees.
+	MaxLandedPrice money.Money
+	// Seller, if not empty, must be part of who sells the
+	// product, like "Amazon" for "Amazon.com" or "Amazon.de".
+	Seller string
+	// InStock requires the product to be in stock.
+	InStock bool
+	// MaxUnits is the max units bought on each Period,
+	// zero means one unit.
+	MaxUnits int
+	// Period is the period of MaxUnits, zero means forever.
+	Period time.Duration
+}
+
+// Check is the result of checking a condition of a rule.
+type Check struct {
+	Name   string
+	OK     bool
+	Reason string
+}
+
+// Evaluation is the result of evaluating a rule against
+// the current state of its product.
+type Evaluation struct {
+	Rule    Rule
+	Product product.Product
+	Checks  []Check
+	// Purchase is the purchase made, nil if nothing was bought.
+	Purchase *buy.Purchase
+}
+
+// Buyer buys products.
+type Buyer interface {
+	Buy(ctx context.Context, link string, maxPrice money.Money) (*buy.Purchase, error)
+}
+
+// BuyerFunc is a function that is a Buyer.
+type BuyerFunc func(ctx context.Context, link string, maxPrice money.Money) (*buy.Purchase, error)
+
+// Engine evaluates the rules periodically, buying the products of
+// the rules whose conditions are met and recording them on the Ledger.
+// It is NOT safe for concurrent use.
+type Engine struct {
+	Rules  []Rule
+	Ledger *Ledger
+	Buyer  Buyer
+	// Client is used to get products, if nil a default client is used.
+	Client *fetch.Client
+	// DryRun only evaluates the rules, nothing is bought.
+	DryRun bool
+	// Interval is the interval between evaluations, defaults to DefaultInterval.
+	Interval time.Duration
+	// Jitter is the max random duration added to each interval.
+	Jitter time.Duration
+	// OnEvaluation is called with each evaluation, if not nil.
+	OnEvaluation func(Evaluation)
+	// OnError is called with errors that don't stop the engine,
+	// like failures to get a product or to buy it, if nil they are ignored.
+	OnError func(error)
+
+	rand *rand.Rand
+}
+
+const DefaultInterval = 5 * time.Minute
+
+// ErrLedger is a failure to record a purchase on the ledger.
+// The engine stops on it, since the product could be bought again.
+const ErrLedger Error = "purchase ledger failed"
+
+// Link returns the link of the product of the rule.
+func (r Rule) Link(c *fetch.Client) string {
+	return c.URL(r.Domain, "/dp/"+r.ASIN)
+}
+
+// Evaluate evaluates the rule against the current state of its product,
+// without buying it. Products without a price, like when out of stock,
+// are evaluated with the stock and price checks failing.
+func (e *Engine) Evaluate(ctx context.Context, rule Rule) (Evaluation, error) {
+	prod, err := product.GetContext(ctx, e.Client, rule.Link(e.Client))
+	if err != nil && !product.IsPriceMissing(prod, err) {
+		return Evaluation{Rule: rule}, fmt.Errorf("rule %q : %w", rule.Name, err)
+	}
+
+	return Evaluation{
+		Rule:    rule,
+		Product: prod,
+		Checks: []Check{
+			checkStock(rule, prod),
+			checkPrice(rule, prod),
+			checkSeller(rule, prod),
+			e.checkUnits(rule),
+		},
+	}, nil
+}
+
+// Check evaluates all the rules once, buying the products of the rules
+// whose conditions are met, unless on DryRun. Rules that fail are
+// reported on the error, a product.Errors with an error for each
+// failed rule, with the evaluations of the others. If a purchase
+// can't be recorded on the ledger the remaining rules are not checked.
+func (e *Engine) Check(ctx context.Context) ([]Evaluation, error) {
+	evaluations := []Evaluation{}
+	errs := []error{}
+
+	for _, rule := range e.Rules {
+		ev, err := e.check(ctx, rule)
+		if err != nil {
+			errs = append(errs, err)
+			e.onError(err)
+		}
+		if ev.Product.URL != "" {
+			evaluations = append(evaluations, ev)
+			if e.OnEvaluation != nil {
+				e.OnEvaluation(ev)
+			}
+		}
+		if errors.Is(err, ErrLedger) {
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return evaluations, product.Errors(errs)
+	}
+	return evaluations, nil
+}
+
+// Run checks the rules until the context is cancelled, returning the
+// context error, or until a purchase can't be recorded on the ledger,
+// returning an ErrLedger error, since it could be bought again.
+func (e *Engine) Run(ctx context.Context) error {
+	for {
+		_, err := e.Check(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrLedger) {
+			return err
+		}
+		if err := fetch.Sleep(ctx, e.nextInterval()); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *Engine) check(ctx context.Context, rule Rule) (Evaluation, error) {
+	ev, err := e.Evaluate(ctx, rule)
+	if err != nil || !ev.OK() || e.DryRun {
+		return ev, err
+	}
+
+	if e.Buyer == nil || e.Ledger == nil {
+		return ev, fmt.Errorf("rule %q fired but the engine has no buyer or ledger", rule.Name)
+	}
+
+	// The purchase is recorded as pending before buying, so if the
+	// process dies while buying it is not bought again after a restart.
+	now := time.Now()
+	entry := Entry{
+		ID:     fmt.Sprintf("%s-%d", rule.Name, now.UnixNano()),
+		Rule:   rule.Name,
+		Domain: rule.Domain,
+		ASIN:   rule.ASIN,
+		Time:   now,
+		Status: Pending,
+	}
+	if err := e.Ledger.Add(entry); err != nil {
+		return ev, fmt.Errorf("%w : rule %q not bought : %v", ErrLedger, rule.Name, err)
+	}
+
+	link := rule.Link(e.Client)
+	purchase, err := e.Buyer.Buy(ctx, link, rule.MaxLandedPrice)
+	if err != nil {
+		entry.Status = Failed
+		if lerr := e.Ledger.Add(entry); lerr != nil {
+			return ev, fmt.Errorf("%w : rule %q failed buying %q (%v) and it stays pending : %v", ErrLedger, rule.Name, link, err, lerr)
+		}
+		return ev, fmt.Errorf("rule %q buying %q : %w", rule.Name, link, err)
+	}
+	ev.Purchase = purchase
+
+	entry.Status = Completed
+	entry.Price = purchase.Price
+	entry.LandedPrice = purchase.LandedPrice
+	if err := e.Ledger.Add(entry); err != nil {
+		return ev, fmt.Errorf("%w : rule %q bought %q but it stays pending : %v", ErrLedger, rule.Name, link, err)
+	}
+	return ev, nil
+}
+
+func (e *Engine) checkUnits(rule Rule) Check {
+	maxUnits := rule.MaxUnits
+	if maxUnits <= 0 {
+		maxUnits = 1
+	}
+
+	since, period := time.Time{}, "ever"
+	if rule.Period > 0 {
+		since, period = time.Now().Add(-rule.Period), "in the last "+rule.Period.String()
+	}
+
+	bought := 0
+	if e.Ledger != nil {
+		bought = e.Ledger.Count(rule.Name, since)
+	}
+
+	return Check{
+		Name:   "units",
+		OK:     bought < maxUnits,
+		Reason: fmt.Sprintf("%d of max %d units bought %s", bought, maxUnits, period),
+	}
+}
+
+func (e *Engine) nextInterval() time.Duration {
+	interval := e.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if e.Jitter > 0 {
+		if e.rand == nil {
+			e.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+		interval += time.Duration(e.rand.Int63n(int64(e.Jitter)))
+	}
+	return interval
+}
+
+func (e *Engine) onError(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
+
+// OK returns true if all the conditions of the rule are met.
+func (ev Evaluation) OK() bool {
+	for _, check := range ev.Checks {
+		if !check.OK {
+			return false
+		}
+	}
+	return len(ev.Checks) > 0
+}
+
+// Explain explains why the rule did or didn't fire, with one line
+// for the rule and one line for each of its conditions.
+func (ev Evaluation) Explain() string {
+	res := strings.Builder{}
+
+	outcome := "not fired"
+	switch {
+	case ev.Purchase != nil:
+		outcome = "fired, bought"
+	case ev.OK():
+		outcome = "fired"
+	}
+	fmt.Fprintf(&res, "rule %q (%s on %s): %s\n", ev.Rule.Name, ev.Rule.ASIN, ev.Rule.Domain, outcome)
+
+	for _, check := range ev.Checks {
+		status := "ok"
+		if !check.OK {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&res, "  [%s] %s: %s\n", status, check.Name, check.Reason)
+	}
+	return res.String()
+}
+
+// Buy calls f.
+func (f BuyerFunc) Buy(ctx context.Context, link string, maxPrice money.Money) (*buy.Purchase, error) {
+	return f(ctx, link, maxPrice)
+}
+
+func checkStock(rule Rule, prod product.Product) Check {
+	check := Check{Name: "in stock", OK: true}
+	switch {
+	case prod.Price.IsZero():
+		// Amazon only has no price for products that can't be bought
+		check.OK = false
+		check.Reason = fmt.Sprintf("no price, availability %q", prod.Availability)
+	case !rule.InStock:
+		check.Reason = fmt.Sprintf("not required, availability %q", prod.Availability)
+	case prod.InStock():
+		check.Reason = fmt.Sprintf("availability %q", prod.Availability)
+	default:
+		check.OK = false
+		check.Reason = fmt.Sprintf("out of stock, availability %q", prod.Availability)
+	}
+	return check
+}
+
+func checkPrice(rule Rule, prod product.Product) Check {
+	check := Check{Name: "landed price"}
+	if prod.Price.IsZero() {
+		check.Reason = "no price"
+		return check
+	}
+
+	landed, err := prod.LandedPrice()
+	if err != nil {
+		check.Reason = err.Error()
+		return check
+	}
+
+	cmp, err := landed.Cmp(rule.MaxLandedPrice)
+	if err != nil {
+		check.Reason = err.Error()
+		return check
+	}
+
+	check.OK = cmp <= 0
+	relation := "at or below"
+	if !check.OK {
+		relation = "above"
+	}
+	check.Reason = fmt.Sprintf("%v (price %v, shipping %v, import fees %v) is %s max %v",
+		landed, prod.Price, prod.Shipping, prod.ImportFees, relation, rule.MaxLandedPrice)
+	return check
+}
+
+// checkSeller checks the seller of the buy box. Products only available
+// from other sellers fail when a seller is required, since the buyer
+// buys the best offer of any of them.
+func checkSeller(rule Rule, prod product.Product) Check {
+	check := Check{Name: "seller", OK: true}
+	switch {
+	case rule.Seller == "":
+		check.Reason = fmt.Sprintf("any seller, sold by %q", prod.SoldBy)
+	case prod.Stock().Status == availability.OtherSellers:
+		check.OK = false
+		check.Reason = fmt.Sprintf("only available from other sellers, want %q", rule.Seller)
+	case strings.Contains(strings.ToLower(prod.SoldBy), strings.ToLower(rule.Seller)):
+		check.Reason = fmt.Sprintf("sold by %q", prod.SoldBy)
+	default:
+		check.OK = false
+		check.Reason = fmt.Sprintf("sold by %q, want %q", prod.SoldBy, rule.Seller)
+	}
+	return check
+}
@@ -0,0 +1,390 @@
+package rules_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/amazontest"
+	"github.com/katcipis/amazoner/buy"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/rules"
+)
+
+// client has no rate limit, so tests run fast
+var client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+func TestEngineEvaluate(t *testing.T) {
+	type Test struct {
+		name     string
+		page     string
+		rule     rules.Rule
+		bought   int
+		wantOK   []bool
+		wantFire bool
+	}
+
+	usd := func(amount int64) money.Money {
+		return money.Money{Amount: amount, Currency: "USD"}
+	}
+
+	tests := []Test{
+		{
+			name:     "AllConditionsMet",
+			page:     amazontest.Fixture("product_deal.html"),
+			rule:     rules.Rule{ASIN: "B08KY322TH", MaxLandedPrice: usd(60000), Seller: "Amazon", InStock: true},
+			wantOK:   []bool{true, true, true, true},
+			wantFire: true,
+		},
+		{
+			name:   "LandedPriceAbove",
+			page:   amazontest.Fixture("product_deal.html"),
+			rule:   rules.Rule{ASIN: "B08KY322TH", MaxLandedPrice: usd(50000), Seller: "Amazon", InStock: true},
+			wantOK: []bool{true, false, true, true},
+		},
+		{
+			name:   "OtherSeller",
+			page:   amazontest.Fixture("product_price_inside_buybox.html"),
+			rule:   rules.Rule{ASIN: "B0856BVRFL", MaxLandedPrice: usd(100000), Seller: "amazon"},
+			wantOK: []bool{true, true, false, true},
+		},
+		{
+			name:   "OnlyFromOtherSellers",
+			page:   amazontest.Fixture("product_other_sellers.html"),
+			rule:   rules.Rule{ASIN: "B07MQ36Z6L", MaxLandedPrice: usd(100000), Seller: "Amazon", InStock: true},
+			wantOK: []bool{true, true, false, true},
+		},
+		{
+			name:     "OnlyFromOtherSellersAnySeller",
+			page:     amazontest.Fixture("product_other_sellers.html"),
+			rule:     rules.Rule{ASIN: "B07MQ36Z6L", MaxLandedPrice: usd(100000), InStock: true},
+			wantOK:   []bool{true, true, true, true},
+			wantFire: true,
+		},
+		{
+			name:   "OutOfStock",
+			page:   amazontest.Fixture("product_unavailable.html"),
+			rule:   rules.Rule{ASIN: "B0856BVRFL", MaxLandedPrice: usd(100000), InStock: true},
+			wantOK: []bool{false, true, true, true},
+		},
+		{
+			name:     "OutOfStockNotRequired",
+			page:     amazontest.Fixture("product_unavailable.html"),
+			rule:     rules.Rule{ASIN: "B0856BVRFL", MaxLandedPrice: usd(100000)},
+			wantOK:   []bool{true, true, true, true},
+			wantFire: true,
+		},
+		{
+			name:   "OutOfStockWithoutPrice",
+			page:   amazontest.Fixture("product_out_of_stock.html"),
+			rule:   rules.Rule{ASIN: "B0856BVRFL", MaxLandedPrice: usd(100000)},
+			wantOK: []bool{false, false, true, true},
+		},
+		{
+			name:   "UnitsBought",
+			page:   amazontest.Fixture("product_deal.html"),
+			rule:   rules.Rule{ASIN: "B08KY322TH", MaxLandedPrice: usd(60000), MaxUnits: 2, Period: time.Hour},
+			bought: 2,
+			wantOK: []bool{true, true, true, false},
+		},
+		{
+			name:     "UnitsBoughtBeforePeriod",
+			page:     amazontest.Fixture("product_deal.html"),
+			rule:     rules.Rule{ASIN: "B08KY322TH", MaxLandedPrice: usd(60000), Period: time.Nanosecond},
+			bought:   1,
+			wantOK:   []bool{true, true, true, true},
+			wantFire: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := amazontest.NewServer(t)
+			server.Handle("/dp/"+test.rule.ASIN, amazontest.Page{File: test.page})
+
+			test.rule.Name = test.name
+			test.rule.Domain = server.Domain()
+
+			ledger := openLedger(t)
+			for i := 0; i < test.bought; i++ {
+				addEntry(t, ledger, rules.Entry{Rule: test.rule.Name, Time: time.Now()})
+			}
+
+			e := &rules.Engine{Client: client, Ledger: ledger}
+			ev, err := e.Evaluate(context.Background(), test.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotOK := []bool{}
+			for _, check := range ev.Checks {
+				gotOK = append(gotOK, check.OK)
+			}
+			if !reflect.DeepEqual(gotOK, test.wantOK) {
+				t.Errorf("got checks %v; want %v\n%s", gotOK, test.wantOK, ev.Explain())
+			}
+			if ev.OK() != test.wantFire {
+				t.Errorf("got fire %t; want %t\n%s", ev.OK(), test.wantFire, ev.Explain())
+			}
+		})
+	}
+}
+
+func TestEngineCheck(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/dp/B08KY322TH", amazontest.Page{File: amazontest.Fixture("product_deal.html")})
+
+	rule := rules.Rule{
+		Name:           "asus rtx 3070",
+		Domain:         server.Domain(),
+		ASIN:           "B08KY322TH",
+		MaxLandedPrice: money.Money{Amount: 60000, Currency: "USD"},
+		Seller:         "Amazon",
+		InStock:        true,
+		MaxUnits:       1,
+		Period:         7 * 24 * time.Hour,
+	}
+
+	ledgerPath := filepath.Join(t.TempDir(), "ledger.jsonl")
+	ledger, err := rules.OpenLedger(ledgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buys := []string{}
+	buyer := rules.BuyerFunc(func(ctx context.Context, link string, maxPrice money.Money) (*buy.Purchase, error) {
+		buys = append(buys, link)
+		if maxPrice != rule.MaxLandedPrice {
+			t.Errorf("got max price %v; want %v", maxPrice, rule.MaxLandedPrice)
+		}
+		if entries := ledger.Entries(); len(entries) != 1 || entries[0].Status != rules.Pending {
+			t.Errorf("got ledger entries %+v while buying; want a pending purchase", entries)
+		}
+		return &buy.Purchase{
+			Price:       money.Money{Amount: 54999, Currency: "USD"},
+			LandedPrice: money.Money{Amount: 54999, Currency: "USD"},
+		}, nil
+	})
+
+	e := &rules.Engine{Rules: []rules.Rule{rule}, Ledger: ledger, Buyer: buyer, Client: client, DryRun: true}
+
+	evs, err := e.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buys) != 0 {
+		t.Fatalf("got buys %v on dry run; want none", buys)
+	}
+	if len(evs) != 1 || !evs[0].OK() || evs[0].Purchase != nil {
+		t.Fatalf("got evaluations %+v; want rule fired without purchase", evs)
+	}
+
+	e.DryRun = false
+	evs, err = e.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buys) != 1 || buys[0] != server.URL+"/dp/B08KY322TH" {
+		t.Fatalf("got buys %v; want product bought once", buys)
+	}
+	if evs[0].Purchase == nil {
+		t.Fatalf("got no purchase on evaluation\n%s", evs[0].Explain())
+	}
+	if !strings.Contains(evs[0].Explain(), "fired, bought") {
+		t.Errorf("got explanation %q; want it to say the product was bought", evs[0].Explain())
+	}
+
+	entries := ledger.Entries()
+	if len(entries) != 1 || entries[0].Status != rules.Completed || entries[0].LandedPrice.Amount != 54999 {
+		t.Errorf("got ledger entries %+v; want the completed purchase", entries)
+	}
+
+	if err := ledger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restarting must not buy it again
+	ledger, err = rules.OpenLedger(ledgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ledger.Close()
+
+	e.Ledger = ledger
+	evs, err = e.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buys) != 1 {
+		t.Fatalf("got buys %v; want product bought only once", buys)
+	}
+
+	explain := evs[0].Explain()
+	want := "rule \"asus rtx 3070\" (B08KY322TH on " + server.Domain() + "): not fired\n"
+	if !strings.HasPrefix(explain, want) {
+		t.Errorf("got explanation %q; want prefix %q", explain, want)
+	}
+	if !strings.Contains(explain, "[FAIL] units: 1 of max 1 units bought in the last 168h0m0s") {
+		t.Errorf("got explanation %q; want units failure", explain)
+	}
+}
+
+func TestEngineCheckFailures(t *testing.T) {
+	server := amazontest.NewServer(t)
+
+	e := &rules.Engine{
+		Rules:  []rules.Rule{{Name: "missing", Domain: server.Domain(), ASIN: "B000000000"}},
+		Client: client,
+	}
+
+	var errs []error
+	e.OnError = func(err error) { errs = append(errs, err) }
+
+	evs, err := e.Check(context.Background())
+	if err == nil {
+		t.Fatal("want error on missing product")
+	}
+	if len(evs) != 0 {
+		t.Errorf("got evaluations %v; want none", evs)
+	}
+	if len(errs) != 1 {
+		t.Errorf("got errors %v; want one", errs)
+	}
+}
+
+func TestEngineCheckWithoutPrice(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/dp/B0856BVRFL", amazontest.Page{File: amazontest.Fixture("product_out_of_stock.html")})
+
+	e := &rules.Engine{
+		Rules: []rules.Rule{{
+			Name:           "out of stock",
+			Domain:         server.Domain(),
+			ASIN:           "B0856BVRFL",
+			MaxLandedPrice: money.Money{Amount: 100000, Currency: "USD"},
+		}},
+		Client: client,
+		DryRun: true,
+	}
+
+	evs, err := e.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].OK() {
+		t.Fatalf("got evaluations %+v; want the rule not fired", evs)
+	}
+
+	explain := evs[0].Explain()
+	for _, want := range []string{"[FAIL] in stock: no price", "[FAIL] landed price: no price"} {
+		if !strings.Contains(explain, want) {
+			t.Errorf("got explanation %q; want %q", explain, want)
+		}
+	}
+}
+
+func TestEngineCheckBuyFailure(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/dp/B08KY322TH", amazontest.Page{File: amazontest.Fixture("product_deal.html")})
+
+	rule := rules.Rule{
+		Name:           "asus rtx 3070",
+		Domain:         server.Domain(),
+		ASIN:           "B08KY322TH",
+		MaxLandedPrice: money.Money{Amount: 60000, Currency: "USD"},
+	}
+
+	buys := 0
+	buyer := rules.BuyerFunc(func(ctx context.Context, link string, maxPrice money.Money) (*buy.Purchase, error) {
+		buys++
+		return nil, buy.ErrOutOfStock
+	})
+
+	ledger := openLedger(t)
+	e := &rules.Engine{Rules: []rules.Rule{rule}, Ledger: ledger, Buyer: buyer, Client: client}
+
+	for i := 0; i < 2; i++ {
+		_, err := e.Check(context.Background())
+		if !errors.Is(err, buy.ErrOutOfStock) {
+			t.Fatalf("got err %v; want %v", err, buy.ErrOutOfStock)
+		}
+	}
+
+	// Failed purchases don't count as bought, so it is tried again
+	if buys != 2 {
+		t.Errorf("got %d buys; want 2", buys)
+	}
+	for _, entry := range ledger.Entries() {
+		if entry.Status != rules.Failed {
+			t.Errorf("got ledger entry %+v; want failed", entry)
+		}
+	}
+}
+
+func TestEngineRunLedgerFailure(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/dp/B08KY322TH", amazontest.Page{File: amazontest.Fixture("product_deal.html")})
+
+	rule := rules.Rule{
+		Domain:         server.Domain(),
+		ASIN:           "B08KY322TH",
+		MaxLandedPrice: money.Money{Amount: 60000, Currency: "USD"},
+	}
+	first, second := rule, rule
+	first.Name, second.Name = "first", "second"
+
+	buys := 0
+	buyer := rules.BuyerFunc(func(ctx context.Context, link string, maxPrice money.Money) (*buy.Purchase, error) {
+		buys++
+		return &buy.Purchase{}, nil
+	})
+
+	// A closed ledger can't record the purchases
+	ledger := openLedger(t)
+	ledger.Close()
+
+	e := &rules.Engine{
+		Rules:    []rules.Rule{first, second},
+		Ledger:   ledger,
+		Buyer:    buyer,
+		Client:   client,
+		Interval: time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := e.Run(ctx); !errors.Is(err, rules.ErrLedger) {
+		t.Fatalf("got err %v; want %v", err, rules.ErrLedger)
+	}
+	if buys != 0 {
+		t.Errorf("got %d buys; want none without a pending purchase on the ledger", buys)
+	}
+}
+
+func openLedger(t *testing.T) *rules.Ledger {
+	t.Helper()
+
+	ledger, err := rules.OpenLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ledger.Close() })
+	return ledger
+}
+
+func addEntry(t *testing.T, ledger *rules.Ledger, entry rules.Entry) {
+	t.Helper()
+
+	if err := ledger.Add(entry); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/katcipis/amazoner/product"
+)
+
+// Cache stores products details for the Searcher.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the product cached with the given key,
+	// expired products are never returned.
+	Get(key string) (product.Product, bool)
+	// Put caches the product with the given key until the deadline.
+	Put(key string, prod product.Product, deadline time.Time) error
+	// Len returns how many products are cached.
+	Len() int
+}
+
+// MemoryCache is a Cache that keeps products in memory,
+// evicting the least recently used ones when it is full.
+type MemoryCache struct {
+	mu        sync.Mutex
+	lru       *lru
+	evictions uint64
+}
+
+// NewMemoryCache creates a memory cache that holds at most size products,
+// sizes zero or negative disable the cache, nothing is cached.
+func NewMemoryCache(size int) *MemoryCache {
+	return &MemoryCache{lru: newLRU(size)}
+}
+
+// Get returns the cached product with the given key.
+func (c *MemoryCache) Get(key string) (product.Product, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.get(key, time.Now())
+}
+
+// Put caches the product, it never fails.
+func (c *MemoryCache) Put(key string, prod product.Product, deadline time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictions += uint64(c.lru.put(key, prod, deadline))
+	return nil
+}
+
+// Len returns how many products are cached.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.len()
+}
+
+// Evictions returns how many products were evicted from the cache.
+func (c *MemoryCache) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.evictions
+}
+
+// lru is a least recently used cache of products, where
+// each entry also expires after its deadline.
+// It is NOT concurrency safe.
+type lru struct {
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type cacheEntry struct {
+	key      string
+	product  product.Product
+	deadline time.Time
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *lru) get(key string, now time.Time) (product.Product, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return product.Product{}, false
+	}
+
+	entry := elem.Value.(cacheEntry)
+	if now.After(entry.deadline) {
+		c.remove(elem)
+		return product.Product{}, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.product, true
+}
+
+// put adds the product to the cache, returning how many
+// entries were evicted to respect the cache size.
+func (c *lru) put(key string, prod product.Product, deadline time.Time) int {
+	if c.size <= 0 {
+		return 0
+	}
+
+	entry := cacheEntry{key: key, product: prod, deadline: deadline}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return 0
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	evicted := 0
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		evicted++
+	}
+	return evicted
+}
+
+func (c *lru) len() int {
+	return c.order.Len()
+}
+
+func (c *lru) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(cacheEntry)
+	delete(c.entries, entry.key)
+}
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/katcipis/amazoner/marketplace"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+)
+
+// Result is a product found on one of the searched marketplaces,
+// with its prices converted to a common currency so products
+// of different marketplaces can be compared.
+type Result struct {
+	Domain  string
+	Product product.Product
+	// Price and LandedPrice are the product prices converted
+	// to the currency of the search.
+	Price       money.Money
+	LandedPrice money.Money
+}
+
+// SearchDomains performs the search query on all the domains in parallel,
+// returning the products of all of them ordered by their landed price
+// converted to the given currency with the given rates.
+//
+// The min and max prices of the query are converted to the currency of
+// each marketplace. It can produce partial results so you should check
+// for the results even if an error is returned.
+func (s *Searcher) SearchDomains(domains []string, q Query, currency string, rates money.Rates) ([]Result, error) {
+	return s.SearchDomainsContext(context.Background(), domains, q, currency, rates)
+}
+
+// SearchDomainsContext is like SearchDomains but with a context
+// that can cancel the searches.
+func (s *Searcher) SearchDomainsContext(
+	ctx context.Context,
+	domains []string,
+	q Query,
+	currency string,
+	rates money.Rates,
+) ([]Result, error) {
+	type domainResults struct {
+		results []Result
+		errs    []error
+	}
+
+	all := make([]domainResults, len(domains))
+	wg := sync.WaitGroup{}
+
+	for i, domain := range domains {
+		wg.Add(1)
+		go func(i int, domain string) {
+			defer wg.Done()
+			all[i].results, all[i].errs = s.searchDomain(ctx, domain, q, currency, rates)
+		}(i, domain)
+	}
+	wg.Wait()
+
+	results := []Result{}
+	errs := []error{}
+	for _, dr := range all {
+		results = append(results, dr.results...)
+		errs = append(errs, dr.errs...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].LandedPrice.Amount < results[j].LandedPrice.Amount
+	})
+
+	if ctx.Err() != nil {
+		return results, ctx.Err()
+	}
+	return results, toErr(errs)
+}
+
+func (s *Searcher) searchDomain(ctx context.Context, domain string, q Query, currency string, rates money.Rates) ([]Result, []error) {
+	var err error
+	local := marketplace.Of(domain).Currency
+
+	if q.MinPrice, err = convertFilter(rates, q.MinPrice, local); err != nil {
+		return nil, []error{fmt.Errorf("domain %q : min price : %w", domain, err)}
+	}
+	if q.MaxPrice, err = convertFilter(rates, q.MaxPrice, local); err != nil {
+		return nil, []error{fmt.Errorf("domain %q : max price : %w", domain, err)}
+	}
+
+	products, err := s.SearchContext(ctx, domain, q)
+
+	errs := []error{}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("domain %q : %w", domain, err))
+	}
+
+	results := make([]Result, 0, len(products))
+	for _, prod := range products {
+		res, err := convertResult(domain, prod, currency, rates)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("domain %q : url %q : %w", domain, prod.URL, err))
+			continue
+		}
+		results = append(results, res)
+	}
+
+	return results, errs
+}
+
+// convertResult converts the prices of the product to the currency.
+// Prices parsed without currency are on the currency of the product
+// price, or of the marketplace if the price has none either.
+func convertResult(domain string, prod product.Product, currency string, rates money.Rates) (Result, error) {
+	local := prod.Price.Currency
+	if local == "" {
+		local = marketplace.Of(domain).Currency
+	}
+	prod.Price = prod.Price.In(local)
+	prod.Shipping = prod.Shipping.In(local)
+	prod.ImportFees = prod.ImportFees.In(local)
+
+	landed, err := prod.LandedPrice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{Domain: domain, Product: prod}
+	if res.Price, err = rates.Convert(prod.Price, currency); err != nil {
+		return Result{}, err
+	}
+	if res.LandedPrice, err = rates.Convert(landed, currency); err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
+
+// convertFilter converts a price filter of the query to the currency
+// of the marketplace, filters without currency are used as they are.
+func convertFilter(rates money.Rates, m money.Money, currency string) (money.Money, error) {
+	if m.IsZero() || m.Currency == "" {
+		return m, nil
+	}
+	return rates.Convert(m, currency)
+}
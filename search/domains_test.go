@@ -0,0 +1,122 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/katcipis/amazoner/amazontest"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+	"github.com/katcipis/amazoner/search"
+)
+
+func TestSearchDomains(t *testing.T) {
+	usServer := amazontest.NewServer(t)
+	euServer := amazontest.NewServer(t)
+
+	for server, page := range map[*amazontest.Server]string{
+		usServer: amazontest.Fixture("product_priceblock_ourprice.html"),
+		euServer: amazontest.Fixture("product_eur.html"),
+	} {
+		server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+		for _, path := range resultsPaths {
+			server.Handle(path, amazontest.Page{File: page})
+		}
+	}
+
+	searcher := search.New(0)
+	searcher.Client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+	rates := money.Rates{Base: "USD", Rates: map[string]float64{"EUR": 1.2}}
+	domains := []string{euServer.Domain(), usServer.Domain()}
+
+	results, err := searcher.SearchDomains(domains, rtxQuery, "USD", rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2*len(resultsPaths) {
+		t.Fatalf("got %d results; want %d", len(results), 2*len(resultsPaths))
+	}
+
+	for i, res := range results {
+		// $1,234.56 is cheaper than 1.034,56 € (1241.47 USD)
+		wantDomain := usServer.Domain()
+		wantPrice := money.Money{Amount: 123456, Currency: "USD"}
+		wantOriginal := money.Money{Amount: 123456, Currency: "USD"}
+		if i >= len(resultsPaths) {
+			wantDomain = euServer.Domain()
+			wantPrice = money.Money{Amount: 124147, Currency: "USD"}
+			wantOriginal = money.Money{Amount: 103456, Currency: "EUR"}
+		}
+
+		if res.Domain != wantDomain {
+			t.Errorf("result %d: got domain %q; want %q", i, res.Domain, wantDomain)
+		}
+		if res.Price != wantPrice || res.LandedPrice != wantPrice {
+			t.Errorf("result %d: got price %v landed price %v; want %v", i, res.Price, res.LandedPrice, wantPrice)
+		}
+		if res.Product.Price != wantOriginal {
+			t.Errorf("result %d: got product price %v; want %v", i, res.Product.Price, wantOriginal)
+		}
+	}
+}
+
+func TestSearchDomainsFailures(t *testing.T) {
+	usServer := amazontest.NewServer(t)
+	usServer.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+	for _, path := range resultsPaths {
+		usServer.Handle(path, amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	}
+
+	euServer := amazontest.NewServer(t)
+	euServer.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+	for _, path := range resultsPaths {
+		euServer.Handle(path, amazontest.Page{File: amazontest.Fixture("product_eur.html")})
+	}
+
+	// No search results page
+	missingServer := amazontest.NewServer(t)
+
+	searcher := search.New(0)
+	searcher.Client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+	// No EUR rate, so EUR products can't be compared
+	rates := money.Rates{Base: "USD"}
+	domains := []string{usServer.Domain(), euServer.Domain(), missingServer.Domain()}
+
+	results, err := searcher.SearchDomains(domains, rtxQuery, "USD", rates)
+	if err == nil {
+		t.Fatal("want error")
+	}
+	if len(results) != len(resultsPaths) {
+		t.Fatalf("got %d results; want the %d results of %q", len(results), len(resultsPaths), usServer.Domain())
+	}
+	for _, res := range results {
+		if res.Domain != usServer.Domain() {
+			t.Errorf("got result of domain %q; want only %q", res.Domain, usServer.Domain())
+		}
+	}
+}
+
+func TestConvertResultWithoutCurrency(t *testing.T) {
+	rates := money.Rates{Base: "USD", Rates: map[string]float64{"EUR": 1.2}}
+	prod := product.Product{
+		URL:   "https://www.amazon.de/dp/B08HR7SV3M",
+		Price: money.Money{Amount: 103456},
+	}
+
+	res, err := search.ConvertResult("www.amazon.de", prod, "USD", rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Prices without currency are on the currency of the marketplace
+	if want := (money.Money{Amount: 103456, Currency: "EUR"}); res.Product.Price != want {
+		t.Errorf("got product price %v; want %v", res.Product.Price, want)
+	}
+	want := money.Money{Amount: 124147, Currency: "USD"}
+	if res.Price != want || res.LandedPrice != want {
+		t.Errorf("got price %v landed price %v; want %v", res.Price, res.LandedPrice, want)
+	}
+}
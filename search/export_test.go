@@ -0,0 +1,5 @@
+package search
+
+var ParseResultsPage = parseResultsPage
+
+var ConvertResult = convertResult
@@ -0,0 +1,162 @@
+package search
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/katcipis/amazoner/jsonl"
+	"github.com/katcipis/amazoner/product"
+)
+
+// FileCache is a Cache persisted on a file, so cached products
+// can be reused across restarts.
+//
+// Products are appended to the file as JSON lines and the file is
+// compacted when opened, removing expired and overwritten products.
+type FileCache struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries map[string]fileCacheEntry
+}
+
+type fileCacheEntry struct {
+	Key      string          `json:"key"`
+	Product  product.Product `json:"product"`
+	Deadline time.Time       `json:"deadline"`
+}
+
+// OpenFileCache opens the file cache on the given path,
+// creating the file if it doesn't exist.
+// The cache must be closed after use.
+func OpenFileCache(path string) (*FileCache, error) {
+	entries, err := loadFileCache(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := compactFileCache(path, entries); err != nil {
+		return nil, err
+	}
+
+	file, err := jsonl.OpenAppend(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileCache{
+		file:    file,
+		entries: entries,
+	}, nil
+}
+
+// Get returns the cached product with the given key.
+func (c *FileCache) Get(key string) (product.Product, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return product.Product{}, false
+	}
+	if time.Now().After(entry.Deadline) {
+		delete(c.entries, key)
+		return product.Product{}, false
+	}
+	return entry.Product, true
+}
+
+// Put caches the product, persisting it on the file.
+func (c *FileCache) Put(key string, prod product.Product, deadline time.Time) error {
+	entry := fileCacheEntry{
+		Key:      key,
+		Product:  prod,
+		Deadline: deadline,
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing file cache : %v", err)
+	}
+
+	c.entries[key] = entry
+	return nil
+}
+
+// Len returns how many products are cached.
+func (c *FileCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// Close closes the cache file.
+func (c *FileCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.file.Close()
+}
+
+func loadFileCache(path string) (map[string]fileCacheEntry, error) {
+	entries := map[string]fileCacheEntry{}
+	now := time.Now()
+
+	err := jsonl.Load(path, func(line []byte) error {
+		var entry fileCacheEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+		if now.After(entry.Deadline) {
+			delete(entries, entry.Key)
+			return nil
+		}
+		entries[entry.Key] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading file cache %q : %v", path, err)
+	}
+	return entries, nil
+}
+
+// compactFileCache rewrites the cache file with only the given entries.
+// The file is replaced atomically, so a failure never loses the cache.
+func compactFileCache(path string, entries map[string]fileCacheEntry) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
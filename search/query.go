@@ -0,0 +1,189 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/katcipis/amazoner/marketplace"
+	"github.com/katcipis/amazoner/money"
+)
+
+// Query describes a search for products.
+type Query struct {
+	// Keywords searched, like "nvidia rtx 3070".
+	Keywords string
+
+	// MinPrice and MaxPrice filter products by price, zero means no filter.
+	// Amazon only filters prices by whole units, so the minimum price
+	// is rounded down and the maximum price is rounded up.
+	MinPrice money.Money
+	MaxPrice money.Money
+
+	// Sort is the order of the results, defaults to Amazon's relevance.
+	Sort Sort
+
+	// Department restricts the search to a department, like
+	// "computers" or "electronics" (the "i" parameter of the search).
+	Department string
+
+	// PrimeOnly restricts the search to Prime eligible products.
+	// It is only supported on www.amazon.com, see ValuesFor.
+	PrimeOnly bool
+
+	// Condition restricts the search to new or used products.
+	// It is only supported on www.amazon.com, see ValuesFor.
+	Condition Condition
+
+	// Brand restricts the search to products of the given brand.
+	Brand string
+
+	// Seller restricts the search to products sold by the seller
+	// with the given ID, like "ATVPDKIKX0DER" for Amazon itself.
+	Seller string
+
+	// MaxPages is the max number of results pages parsed,
+	// following the next page of each results page.
+	// Defaults to 1, only the first page.
+	MaxPages int
+
+	// MaxResults is the max number of products URLs,
+	// zero means no limit.
+	MaxResults int
+}
+
+// Sort is the order of the search results.
+type Sort string
+
+const (
+	SortRelevance   Sort = ""
+	SortPriceAsc    Sort = "price-asc-rank"
+	SortPriceDesc   Sort = "price-desc-rank"
+	SortNewest      Sort = "date-desc-rank"
+	SortReviews     Sort = "review-rank"
+	SortBestSellers Sort = "exact-aware-popularity-rank"
+	SortFeatured    Sort = "relevanceblender"
+)
+
+// Condition is the condition of a product.
+type Condition string
+
+const (
+	ConditionAny     Condition = ""
+	ConditionNew     Condition = "new"
+	ConditionUsed    Condition = "used"
+	ConditionRenewed Condition = "renewed"
+)
+
+// Refinements are the Amazon search refinements ("rh" parameter)
+// used for the filters that don't have their own parameter.
+const (
+	primeRefinement     = "p_85:"
+	brandRefinement     = "p_89:"
+	conditionRefinement = "p_n_condition-type:"
+)
+
+// refinementIDs are the IDs of the refinements that are nodes of the
+// browse tree of a marketplace, so each marketplace has its own IDs.
+type refinementIDs struct {
+	prime      string
+	conditions map[Condition]string
+}
+
+// marketplaceRefinements has the refinement IDs of the marketplaces
+// they are known, other marketplaces can't use these refinements.
+var marketplaceRefinements = map[string]refinementIDs{
+	"www.amazon.com": {
+		prime: "2470955011",
+		conditions: map[Condition]string{
+			ConditionNew:     "6461716011",
+			ConditionUsed:    "6461718011",
+			ConditionRenewed: "6461717011",
+		},
+	},
+}
+
+var conditions = map[Condition]struct{}{
+	ConditionAny:     {},
+	ConditionNew:     {},
+	ConditionUsed:    {},
+	ConditionRenewed: {},
+}
+
+// rhEscaper escapes the separators of the refinements on their values.
+var rhEscaper = strings.NewReplacer("%", "%25", ",", "%2C", ":", "%3A", "|", "%7C")
+
+var sorts = map[Sort]struct{}{
+	SortRelevance:   {},
+	SortPriceAsc:    {},
+	SortPriceDesc:   {},
+	SortNewest:      {},
+	SortReviews:     {},
+	SortBestSellers: {},
+	SortFeatured:    {},
+}
+
+// Values returns the URL query parameters of the search on
+// www.amazon.com, see ValuesFor.
+func (q Query) Values() (url.Values, error) {
+	return q.ValuesFor("www.amazon.com")
+}
+
+// ValuesFor returns the URL query parameters of the search on the
+// given domain. The PrimeOnly and Condition filters use IDs of the
+// marketplace, it fails with ErrInvalidQuery if they are used on
+// a domain whose IDs are unknown.
+func (q Query) ValuesFor(domain string) (url.Values, error) {
+	if strings.TrimSpace(q.Keywords) == "" {
+		return nil, fmt.Errorf("%w : keywords are obligatory", ErrInvalidQuery)
+	}
+
+	v := url.Values{}
+	v.Set("k", q.Keywords)
+
+	if !q.MinPrice.IsZero() {
+		v.Set("low-price", itoa(q.MinPrice.Floor()))
+	}
+	if !q.MaxPrice.IsZero() {
+		v.Set("high-price", itoa(q.MaxPrice.Ceil()))
+	}
+
+	if _, ok := sorts[q.Sort]; !ok {
+		return nil, fmt.Errorf("%w : unknown sort %q", ErrInvalidQuery, q.Sort)
+	}
+	if q.Sort != SortRelevance {
+		v.Set("s", string(q.Sort))
+	}
+
+	if q.Department != "" {
+		v.Set("i", q.Department)
+	}
+	if q.Seller != "" {
+		v.Set("me", q.Seller)
+	}
+
+	if _, ok := conditions[q.Condition]; !ok {
+		return nil, fmt.Errorf("%w : unknown condition %q", ErrInvalidQuery, q.Condition)
+	}
+
+	ids, known := marketplaceRefinements[marketplace.Of(domain).Domain]
+	if (q.PrimeOnly || q.Condition != ConditionAny) && !known {
+		return nil, fmt.Errorf("%w : prime and condition filters are not supported on %q", ErrInvalidQuery, domain)
+	}
+
+	refinements := []string{}
+	if q.PrimeOnly {
+		refinements = append(refinements, primeRefinement+ids.prime)
+	}
+	if q.Brand != "" {
+		refinements = append(refinements, brandRefinement+rhEscaper.Replace(q.Brand))
+	}
+	if q.Condition != ConditionAny {
+		refinements = append(refinements, conditionRefinement+ids.conditions[q.Condition])
+	}
+	if len(refinements) > 0 {
+		v.Set("rh", strings.Join(refinements, ","))
+	}
+
+	return v, nil
+}
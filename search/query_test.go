@@ -0,0 +1,131 @@
+package search_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/search"
+)
+
+func TestQueryValues(t *testing.T) {
+	type Test struct {
+		name  string
+		query search.Query
+		want  url.Values
+	}
+
+	tests := []Test{
+		{
+			name:  "KeywordsOnly",
+			query: search.Query{Keywords: "rtx 3070"},
+			want:  url.Values{"k": {"rtx 3070"}},
+		},
+		{
+			name: "PricesAreRounded",
+			query: search.Query{
+				Keywords: "rtx 3070",
+				MinPrice: money.Money{Amount: 49999, Currency: "USD"},
+				MaxPrice: money.Money{Amount: 150001, Currency: "USD"},
+			},
+			want: url.Values{
+				"k":          {"rtx 3070"},
+				"low-price":  {"499"},
+				"high-price": {"1501"},
+			},
+		},
+		{
+			name: "AllFilters",
+			query: search.Query{
+				Keywords:   "rtx 3070",
+				Sort:       search.SortPriceAsc,
+				Department: "computers",
+				PrimeOnly:  true,
+				Condition:  search.ConditionUsed,
+				Brand:      "MSI",
+				Seller:     "ATVPDKIKX0DER",
+			},
+			want: url.Values{
+				"k":  {"rtx 3070"},
+				"s":  {"price-asc-rank"},
+				"i":  {"computers"},
+				"me": {"ATVPDKIKX0DER"},
+				"rh": {"p_85:2470955011,p_89:MSI,p_n_condition-type:6461718011"},
+			},
+		},
+		{
+			name: "BrandIsEscaped",
+			query: search.Query{
+				Keywords: "headphones",
+				Brand:    "Bang & Olufsen, Inc: 100%",
+			},
+			want: url.Values{
+				"k":  {"headphones"},
+				"rh": {"p_89:Bang & Olufsen%2C Inc%3A 100%25"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.query.Values()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Encode() != test.want.Encode() {
+				t.Fatalf("got %q; want %q", got.Encode(), test.want.Encode())
+			}
+		})
+	}
+}
+
+func TestQueryValuesFor(t *testing.T) {
+	query := search.Query{Keywords: "rtx 3070", PrimeOnly: true, Brand: "MSI"}
+
+	got, err := query.ValuesFor("amazon.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "p_85:2470955011,p_89:MSI"; got.Get("rh") != want {
+		t.Errorf("got rh %q; want %q", got.Get("rh"), want)
+	}
+
+	// The refinement IDs of other marketplaces are unknown
+	for _, domain := range []string{"www.amazon.de", "127.0.0.1:8080"} {
+		for _, query := range []search.Query{
+			{Keywords: "rtx 3070", PrimeOnly: true},
+			{Keywords: "rtx 3070", Condition: search.ConditionNew},
+		} {
+			_, err := query.ValuesFor(domain)
+			if !errors.Is(err, search.ErrInvalidQuery) {
+				t.Errorf("%s %+v: got err %v; want %v", domain, query, err, search.ErrInvalidQuery)
+			}
+		}
+	}
+
+	got, err = search.Query{Keywords: "rtx 3070", Brand: "MSI"}.ValuesFor("www.amazon.de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "p_89:MSI"; got.Get("rh") != want {
+		t.Errorf("got rh %q; want %q", got.Get("rh"), want)
+	}
+}
+
+func TestQueryValuesFailures(t *testing.T) {
+	tests := map[string]search.Query{
+		"NoKeywords":       {MaxPrice: money.Money{Amount: 100}},
+		"UnknownSort":      {Keywords: "rtx", Sort: "cheapest"},
+		"UnknownCondition": {Keywords: "rtx", Condition: "broken"},
+	}
+
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := query.Values()
+			if !errors.Is(err, search.ErrInvalidQuery) {
+				t.Fatalf("got err %v; want %v", err, search.ErrInvalidQuery)
+			}
+		})
+	}
+}
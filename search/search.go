@@ -1,126 +1,303 @@
 package search
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"github.com/katcipis/amazoner/header"
+	"github.com/katcipis/amazoner/fetch"
 	"github.com/katcipis/amazoner/product"
 )
 
 // Searcher searches for products and provides the found products
 // with an internal cache to avoid getting products details too often.
 //
-// The searcher is NOT concurrency safe.
+// The searcher is safe for concurrent use, concurrent searches share
+// the cache and a product being fetched by one search is not fetched
+// again by another search, it waits for the ongoing fetch instead.
+// The exported fields must not be changed after the first search.
 type Searcher struct {
 	CachePeriod time.Duration
-	cache       map[string]cacheEntry
+	// Client is used to search and get products, if nil
+	// a default client is used.
+	Client *fetch.Client
+	// Workers is how many products details are fetched
+	// concurrently, defaults to product.DefaultWorkers.
+	Workers int
+	// KeepUnpriced keeps the products got without price, like products
+	// out of stock, on the results instead of failing them.
+	KeepUnpriced bool
+
+	mu       sync.Mutex
+	cache    Cache
+	inflight map[string]*call
+	stats    CacheStats
+}
+
+// CacheStats are statistics of the searcher cache usage.
+type CacheStats struct {
+	// Hits is how many products were found on the cache.
+	Hits uint64
+	// Misses is how many products had to be fetched.
+	Misses uint64
+	// Shared is how many products were not on the cache but were
+	// being fetched by another search, so the fetch was shared.
+	Shared uint64
+	// Evictions is how many products were removed from the
+	// cache to respect its size. Only available if the cache
+	// reports its evictions, like the MemoryCache.
+	Evictions uint64
+	// Entries is how many products are on the cache.
+	Entries int
 }
 
 type Error string
 
 const (
-	ErrCaptcha Error = "captcha challenge"
+	// ErrCaptcha is the same as fetch.ErrCaptcha, so captchas
+	// are detected with errors.Is using any of them.
+	ErrCaptcha            = fetch.ErrCaptcha
+	ErrInvalidQuery Error = "invalid query"
 )
 
+// DefaultCacheSize is the max number of products cached by default.
+const DefaultCacheSize = 1000
+
+// call is a product fetch that can be shared by multiple searches.
+type call struct {
+	url     string
+	key     string
+	done    chan struct{}
+	product product.Product
+	err     error
+	// abandoned is true if the search that owned the call was
+	// cancelled before fetching it, so it must be started again.
+	abandoned bool
+}
+
+// New creates a searcher whose cache has the DefaultCacheSize.
 func New(cachePeriod time.Duration) *Searcher {
+	return NewWithCacheSize(cachePeriod, DefaultCacheSize)
+}
+
+// NewWithCacheSize creates a searcher whose cache holds at most
+// cacheSize products, evicting the least recently used ones.
+func NewWithCacheSize(cachePeriod time.Duration, cacheSize int) *Searcher {
+	return NewWithCache(cachePeriod, NewMemoryCache(cacheSize))
+}
+
+// NewWithCache creates a searcher that uses the given cache, like
+// a FileCache to reuse products details across restarts.
+func NewWithCache(cachePeriod time.Duration, cache Cache) *Searcher {
 	return &Searcher{
 		CachePeriod: cachePeriod,
-		cache:       map[string]cacheEntry{},
+		cache:       cache,
+		inflight:    map[string]*call{},
 	}
 }
 
-// Search performs a search with the given parameters and returns
-// a list of products. It can produce partial results so you
-// should check for the products even if an error is returned.
-func (s *Searcher) Search(domain, name string, minPrice, maxPrice uint) ([]product.Product, error) {
-	s.cleanCache()
-	urls, err := Do(domain, name, minPrice, maxPrice)
-	if err != nil {
-		return nil, err
+// Search performs the search query and returns a list of products.
+// It can produce partial results so you should check for the
+// products even if an error is returned. The error is a product.Errors,
+// with a *product.URLError for each product that could not be got.
+func (s *Searcher) Search(domain string, q Query) ([]product.Product, error) {
+	return s.SearchContext(context.Background(), domain, q)
+}
+
+// SearchContext is like Search but with a context that can cancel the
+// search. If the context is cancelled while getting products details
+// the products got so far are returned with the context error.
+func (s *Searcher) SearchContext(ctx context.Context, domain string, q Query) ([]product.Product, error) {
+	urls, searchErr := DoContext(ctx, s.Client, domain, q)
+	if len(urls) == 0 {
+		return nil, searchErr
 	}
 
-	uncachedURLs := []string{}
-	products := []product.Product{}
+	var errs []error
+	if searchErr != nil {
+		errs = append(errs, searchErr)
+	}
 
-	for _, url := range urls {
-		r, ok := s.cache[url]
-		if ok {
-			products = append(products, r.product)
+	fetcher := &product.Fetcher{Client: s.Client, Workers: s.Workers}
+	done := make([]*call, len(urls))
+
+	// Calls abandoned by the searches that owned them, because their
+	// context was cancelled, are started again by this search.
+	pending := make([]int, len(urls))
+	for i := range urls {
+		pending[i] = i
+	}
+
+	for len(pending) > 0 && ctx.Err() == nil {
+		pendingURLs := make([]string, len(pending))
+		for i, index := range pending {
+			pendingURLs[i] = urls[index]
+		}
+
+		calls, owned := s.startCalls(pendingURLs)
+
+		uncachedURLs := make([]string, len(owned))
+		for i, c := range owned {
+			uncachedURLs[i] = c.url
+		}
+		errs = append(errs, s.finishCalls(ctx, owned, fetcher.FetchAll(ctx, uncachedURLs))...)
+
+		abandoned := []int{}
+		for i, c := range calls {
+			select {
+			case <-c.done:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			if c.abandoned {
+				abandoned = append(abandoned, pending[i])
+				continue
+			}
+			done[pending[i]] = c
+		}
+		pending = abandoned
+	}
+
+	products := []product.Product{}
+	for _, c := range done {
+		if c == nil {
+			continue
+		}
+		if c.err != nil && !(s.KeepUnpriced && product.IsPriceMissing(c.product, c.err)) {
+			errs = append(errs, &product.URLError{URL: c.url, Err: c.err})
 			continue
 		}
-		uncachedURLs = append(uncachedURLs, url)
+		products = append(products, c.product)
 	}
 
-	productsGot, err := product.GetProducts(uncachedURLs)
-	s.addCache(productsGot)
-	return append(products, productsGot...), err
+	if ctx.Err() != nil {
+		return products, ctx.Err()
+	}
+	return products, toErr(errs)
 }
 
-// Do performs a search with the given parameters and returns
-// a list of products URLs.
-func Do(domain, name string, minPrice, maxPrice uint) ([]string, error) {
+// Stats returns the cache statistics of the searcher.
+func (s *Searcher) Stats() CacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	entrypointURL := "https://" + domain
+	stats := s.stats
+	stats.Entries = s.cache.Len()
+	if c, ok := s.cache.(interface{ Evictions() uint64 }); ok {
+		stats.Evictions = c.Evictions()
+	}
+	return stats
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	searchQuery := fmt.Sprintf("%s/s", entrypointURL)
+// Do performs the search query and returns a list of products URLs.
+// If the client is nil a default client is used.
+//
+// URLs are not duplicated, even across multiple pages. If a page after
+// the first one fails the URLs found so far are returned with the error.
+func Do(c *fetch.Client, domain string, q Query) ([]string, error) {
+	return DoContext(context.Background(), c, domain, q)
+}
 
-	req, err := http.NewRequest(http.MethodGet, searchQuery, nil)
+// DoContext is like Do but with a context that can cancel the search.
+func DoContext(ctx context.Context, c *fetch.Client, domain string, q Query) ([]string, error) {
+	params, err := q.ValuesFor(domain)
 	if err != nil {
 		return nil, err
 	}
 
-	header.Add(req)
+	maxPages := q.MaxPages
+	if maxPages <= 0 {
+		maxPages = 1
+	}
 
-	q := req.URL.Query()
-	q.Add("k", name)
-	q.Add("low-price", itoa(minPrice))
-	q.Add("high-price", itoa(maxPrice))
-	req.URL.RawQuery = q.Encode()
+	pageURL := c.URL(domain, "/s?"+params.Encode())
+	relurls := []string{}
 
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
+	for page := 1; page <= maxPages && pageURL != ""; page++ {
+		body, err := c.GetContext(ctx, pageURL)
+		if err != nil {
+			err = fmt.Errorf("search query page %d failed : %w", page, err)
+			if page == 1 {
+				return nil, err
+			}
+			return absURLs(c, domain, relurls), err
+		}
+
+		urls, next, err := parseResultsPage(body, page)
+		if err != nil {
+			if page == 1 {
+				return nil, err
+			}
+			return absURLs(c, domain, relurls), fmt.Errorf("search results page %d : %w", page, err)
+		}
 
-	if res.StatusCode != http.StatusOK {
-		// Lazy ignoring the error here
-		resBody, _ := ioutil.ReadAll(res.Body)
-		return nil, fmt.Errorf("main search query failed, unexpected status code %d, body:\n%s\n", res.StatusCode, resBody)
+		relurls = removeDuplicates(append(relurls, urls...))
+		if q.MaxResults > 0 && len(relurls) >= q.MaxResults {
+			relurls = relurls[:q.MaxResults]
+			break
+		}
+
+		if next == "" {
+			break
+		}
+		if pageURL, err = resolveURL(pageURL, next); err != nil {
+			return absURLs(c, domain, relurls), fmt.Errorf("search results page %d : next page : %w", page, err)
+		}
 	}
 
-	urls, err := parseResultsURLs(res.Body)
+	return absURLs(c, domain, relurls), nil
+}
+
+// resolveURL resolves the link found on the page with the given URL,
+// the link may be relative to the page or absolute.
+func resolveURL(pageURL, link string) (string, error) {
+	base, err := url.Parse(pageURL)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-
-	for i, relurl := range urls {
-		absURL := entrypointURL + relurl
-		urls[i] = absURL
+	ref, err := url.Parse(link)
+	if err != nil {
+		return "", err
 	}
-
-	return urls, nil
+	return base.ResolveReference(ref).String(), nil
 }
 
-type cacheEntry struct {
-	product  product.Product
-	deadline time.Time
+func absURLs(c *fetch.Client, domain string, relurls []string) []string {
+	urls := make([]string, len(relurls))
+	for i, relurl := range relurls {
+		urls[i] = c.URL(domain, relurl)
+	}
+	return urls
 }
 
-func parseResultsURLs(html io.Reader) ([]string, error) {
-	doc, err := goquery.NewDocumentFromReader(html)
+// parseResultsPage parses the products URLs and the URL of the
+// next page from the given search results page. The next page URL is
+// empty if there are no more pages.
+func parseResultsPage(html io.Reader, page int) ([]string, string, error) {
+	body, err := ioutil.ReadAll(html)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	// The client already detects captchas, but pages can come from elsewhere
+	if fetch.IsCaptcha(body) {
+		return nil, "", fmt.Errorf("unable to find product URLs : %w", ErrCaptcha)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
 	}
 
 	s := doc.Find(".s-main-slot.s-result-list.s-search-results.sg-row")
@@ -143,18 +320,41 @@ func parseResultsURLs(html io.Reader) ([]string, error) {
 	})
 
 	if len(urls) == 0 {
-		if isCaptchaChallenge(doc) {
-			return nil, fmt.Errorf("unable to find product URLs : %w", ErrCaptcha)
-		}
-
-		return nil, errors.New("unable to find any URLs on search result page")
+		return nil, "", errors.New("unable to find any URLs on search result page")
 	}
 
 	urls = removeStartingWith(urls, "s", "x", "gp")
 	urls = removeReferences(urls)
 	urls = removeDuplicates(urls)
 
-	return urls, nil
+	return urls, parseNextPage(doc, page), nil
+}
+
+// parseNextPage parses the URL of the page after the given page,
+// returning an empty string if there is none.
+func parseNextPage(doc *goquery.Document, page int) string {
+	next, ok := doc.Find("a.s-pagination-next").Attr("href")
+	if ok {
+		return next
+	}
+
+	next, ok = doc.Find("ul.a-pagination li.a-last a").Attr("href")
+	if ok {
+		return next
+	}
+
+	// Fallback to any pagination link to the page after the current one
+	nextPage := strconv.Itoa(page + 1)
+	doc.Find(".s-pagination-container a, ul.a-pagination a").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		href, _ := s.Attr("href")
+		u, err := url.Parse(href)
+		if err != nil || u.Query().Get("page") != nextPage {
+			return true
+		}
+		next = href
+		return false
+	})
+	return next
 }
 
 func removeReferences(urls []string) []string {
@@ -204,62 +404,110 @@ func removeStartingWith(urls []string, resourceNames ...string) []string {
 	return res
 }
 
+// removeDuplicates removes duplicated URLs, keeping the
+// order of the first occurrence of each URL. URLs of the
+// same product (same ASIN) are duplicates even if they differ.
 func removeDuplicates(urls []string) []string {
 	uniq := map[string]struct{}{}
+	res := []string{}
 
 	for _, url := range urls {
-		uniq[url] = struct{}{}
-	}
-
-	i := 0
-	res := make([]string, len(uniq))
-
-	for url, _ := range uniq {
-		res[i] = url
-		i++
+		key := url
+		if asin, err := product.ParseASIN(url); err == nil {
+			key = asin
+		}
+		if _, ok := uniq[key]; ok {
+			continue
+		}
+		uniq[key] = struct{}{}
+		res = append(res, url)
 	}
 
 	return res
 }
 
-func itoa(v uint) string {
-	return strconv.FormatUint(uint64(v), 10)
+func itoa(v int64) string {
+	return strconv.FormatInt(v, 10)
 }
 
+// toErr returns the errors as product.Errors, or nil if there are none.
 func toErr(errs []error) error {
-	// FIXME: Copied from product
 	if len(errs) == 0 {
 		return nil
 	}
-
-	errmsgs := make([]string, len(errs))
-	for i, err := range errs {
-		errmsgs[i] = err.Error()
-	}
-	return errors.New(strings.Join(errmsgs, "\n"))
-}
-
-func isCaptchaChallenge(doc *goquery.Document) bool {
-	return strings.Contains(doc.Text(), "captcha")
+	return product.Errors(errs)
 }
 
 func (e Error) Error() string {
 	return string(e)
 }
 
-func (s *Searcher) addCache(prods []product.Product) {
-	for _, prod := range prods {
-		s.cache[prod.URL] = cacheEntry{
-			product:  prod,
-			deadline: time.Now().Add(s.CachePeriod),
+// startCalls gets the products of the given URLs from the cache or
+// from ongoing fetches, identifying products by their canonical URL,
+// returning a call for each URL and the calls that must be fetched
+// by the caller (owned calls).
+func (s *Searcher) startCalls(urls []string) ([]*call, []*call) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calls := make([]*call, len(urls))
+	owned := []*call{}
+
+	for i, url := range urls {
+		key := product.Key(url)
+		if prod, ok := s.cache.Get(key); ok {
+			s.stats.Hits++
+			calls[i] = &call{url: url, key: key, done: closed, product: prod}
+			continue
 		}
+		if c, ok := s.inflight[key]; ok {
+			s.stats.Shared++
+			calls[i] = c
+			continue
+		}
+
+		s.stats.Misses++
+		c := &call{url: url, key: key, done: make(chan struct{})}
+		s.inflight[key] = c
+		calls[i] = c
+		owned = append(owned, c)
 	}
+
+	return calls, owned
 }
 
-func (s *Searcher) cleanCache() {
-	for k, entry := range s.cache {
-		if time.Now().After(entry.deadline) {
-			delete(s.cache, k)
+// finishCalls caches the fetched products and wakes up
+// all searches waiting for them. Failures to cache products
+// are returned, the products are still available to the searches.
+//
+// Calls that failed because the context of the owner was cancelled
+// are abandoned, instead of failing the searches waiting for them.
+func (s *Searcher) finishCalls(ctx context.Context, owned []*call, results []product.Result) []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	deadline := time.Now().Add(s.CachePeriod)
+
+	for i, res := range results {
+		c := owned[i]
+		c.product, c.err = res.Product, res.Err
+		c.abandoned = ctx.Err() != nil && errors.Is(res.Err, ctx.Err())
+		if res.Err == nil {
+			if err := s.cache.Put(c.key, res.Product, deadline); err != nil {
+				errs = append(errs, fmt.Errorf("caching url %q : %w", c.url, err))
+			}
 		}
+		delete(s.inflight, c.key)
+		close(c.done)
 	}
+
+	return errs
 }
+
+// closed is a closed channel, used by calls that are already done.
+var closed = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
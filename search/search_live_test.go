@@ -0,0 +1,90 @@
+//go:build live
+// +build live
+
+package search_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/search"
+)
+
+func TestSearch(t *testing.T) {
+	// Talks with the real Amazon, run with: go test -tags live ./...
+	// The best we can do is some sort of property conservation
+	// test, so we catch bizarre regressions like returning no results
+	// or results with empty name, etc (although we dont check specific
+	// products or relevance).
+
+	type Test struct {
+		domain     string
+		search     string
+		minPrice   money.Money
+		maxPrice   money.Money
+		minResults uint
+	}
+
+	tests := []Test{
+		{
+			domain:     "www.amazon.com",
+			search:     "nvidia rtx 3070",
+			minPrice:   money.Money{Amount: 50000, Currency: "USD"},
+			maxPrice:   money.Money{Amount: 150000, Currency: "USD"},
+			minResults: 14,
+		},
+	}
+
+	for _, test := range tests {
+		testname := fmt.Sprintf(
+			"%s%sMin%vMax%vWant%d",
+			test.domain,
+			test.search,
+			test.minPrice,
+			test.maxPrice,
+			test.minResults,
+		)
+		t.Run(testname, func(t *testing.T) {
+			res, err := search.Do(nil, test.domain, search.Query{
+				Keywords: test.search,
+				MinPrice: test.minPrice,
+				MaxPrice: test.maxPrice,
+			})
+			if len(res) < int(test.minResults) {
+				t.Errorf("got %d results; want %d", len(res), test.minResults)
+				t.Errorf("results:%v", res)
+				if err != nil {
+					t.Errorf("errors:%v", err)
+				}
+			}
+		})
+		t.Run("Searcher/"+testname, func(t *testing.T) {
+			searcher := search.New(time.Minute)
+			res, err := searcher.Search(test.domain, search.Query{
+				Keywords: test.search,
+				MinPrice: test.minPrice,
+				MaxPrice: test.maxPrice,
+			})
+			if len(res) < int(test.minResults) {
+				t.Errorf("got %d results; want %d", len(res), test.minResults)
+				t.Errorf("results:%v", res)
+				if err != nil {
+					t.Errorf("errors:%v", err)
+				}
+			}
+
+			for i, prod := range res {
+
+				if prod.Name == "" {
+					t.Errorf("prod %d missing name on product", i)
+				}
+
+				if prod.Price.Amount <= 0 {
+					t.Errorf("prod %d missing price on product", i)
+				}
+			}
+		})
+	}
+}
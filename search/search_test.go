@@ -1,77 +1,590 @@
 package search_test
 
 import (
-	"fmt"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/katcipis/amazoner/amazontest"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
 	"github.com/katcipis/amazoner/search"
 )
 
-func TestSearch(t *testing.T) {
-	// The best we can do is some sort of property conservation
-	// test, so we catch bizarre regressions like returning no results
-	// or results with empty name, etc (although we dont check specific
-	// products or relevance).
+// resultsPaths are the products on the search_results.html fixture,
+// on the same order of the page.
+var resultsPaths = []string{
+	"/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4",
+	"/MSI-GeForce-RTX-3070-Architecture/dp/B08KWN2LZG",
+	"/PNY-GeForce-Gaming-Epic-X-Graphics/dp/B08HBJB7YD",
+	"/EVGA-08G-P5-3767-KR-GeForce-Technology-Backplate/dp/B08L8L9TCZ",
+}
+
+// rtxQuery is the query of the search_results.html fixture
+var rtxQuery = search.Query{
+	Keywords: "nvidia rtx 3070",
+	MinPrice: money.Money{Amount: 50000, Currency: "USD"},
+	MaxPrice: money.Money{Amount: 150000, Currency: "USD"},
+}
+
+func TestDo(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+
+	client := &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+	got, err := search.Do(client, server.Domain(), rtxQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := make([]string, len(resultsPaths))
+	for i, path := range resultsPaths {
+		want[i] = server.URL + path
+	}
+	assertURLs(t, got, want)
+}
+
+func TestDoPagination(t *testing.T) {
+	page2Paths := []string{
+		"/Gigabyte-Graphics-WINDFORCE-GV-N3070GAMING-OC-8GD/dp/B08LF1CWT2",
+		"/ASUS-Graphics-DisplayPort-Axial-tech-2-9-Slot/dp/B08KY322TH",
+	}
 
 	type Test struct {
-		domain     string
-		search     string
-		minPrice   uint
-		maxPrice   uint
-		minResults uint
+		name       string
+		maxPages   int
+		maxResults int
+		wantPaths  []string
+		wantPages  int
 	}
 
 	tests := []Test{
 		{
-			domain:     "www.amazon.com",
-			search:     "nvidia rtx 3070",
-			minPrice:   500,
-			maxPrice:   1500,
-			minResults: 14,
+			name:      "FirstPageByDefault",
+			wantPaths: resultsPaths,
+			wantPages: 1,
+		},
+		{
+			name:      "AllPages",
+			maxPages:  5,
+			wantPaths: append(append([]string{}, resultsPaths...), page2Paths...),
+			wantPages: 2,
+		},
+		{
+			name:       "MaxResultsOnSecondPage",
+			maxPages:   5,
+			maxResults: 5,
+			wantPaths:  append(append([]string{}, resultsPaths...), page2Paths[0]),
+			wantPages:  2,
+		},
+		{
+			name:       "MaxResultsOnFirstPage",
+			maxPages:   5,
+			maxResults: 3,
+			wantPaths:  resultsPaths[:3],
+			wantPages:  1,
 		},
 	}
 
 	for _, test := range tests {
-		testname := fmt.Sprintf(
-			"%s%sMin%dMax%dWant%d",
-			test.domain,
-			test.search,
-			test.minPrice,
-			test.maxPrice,
-			test.minResults,
-		)
-		t.Run(testname, func(t *testing.T) {
-			res, err := search.Do(test.domain, test.search, test.minPrice, test.maxPrice)
-			if len(res) < int(test.minResults) {
-				t.Errorf("got %d results; want %d", len(res), test.minResults)
-				t.Errorf("results:%v", res)
-				if err != nil {
-					t.Errorf("errors:%v", err)
+		t.Run(test.name, func(t *testing.T) {
+			server := amazontest.NewServer(t)
+			server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+			server.Handle("/s?page=2", amazontest.Page{File: amazontest.Fixture("search_results_page2.html")})
+
+			client := &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+			query := rtxQuery
+			query.MaxPages = test.maxPages
+			query.MaxResults = test.maxResults
+
+			got, err := search.Do(client, server.Domain(), query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(test.wantPaths) {
+				t.Fatalf("got %d urls %v; want %d", len(got), got, len(test.wantPaths))
+			}
+			for i, path := range test.wantPaths {
+				if want := server.URL + path; got[i] != want {
+					t.Errorf("got url[%d] %q; want %q", i, got[i], want)
 				}
 			}
+			if pages := server.Requests("/s"); pages != test.wantPages {
+				t.Errorf("got %d pages requested; want %d", pages, test.wantPages)
+			}
 		})
-		t.Run("Searcher/"+testname, func(t *testing.T) {
-			searcher := search.New(time.Minute)
-			res, err := searcher.Search(test.domain, test.search, test.minPrice, test.maxPrice)
-			if len(res) < int(test.minResults) {
-				t.Errorf("got %d results; want %d", len(res), test.minResults)
-				t.Errorf("results:%v", res)
-				if err != nil {
-					t.Errorf("errors:%v", err)
-				}
+	}
+}
+
+func TestDoAbsoluteNextPage(t *testing.T) {
+	server := amazontest.NewServer(t)
+
+	// Amazon may link the next page with an absolute URL
+	page, err := ioutil.ReadFile(amazontest.Fixture("search_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page = bytes.Replace(page, []byte(`<a href="/s?k=`), []byte(`<a href="`+server.URL+`/s?k=`), -1)
+	path := filepath.Join(t.TempDir(), "search_results.html")
+	if err := ioutil.WriteFile(path, page, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Handle("/s", amazontest.Page{File: path})
+	server.Handle("/s?page=2", amazontest.Page{File: amazontest.Fixture("search_results_page2.html")})
+
+	client := &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+	query := rtxQuery
+	query.MaxPages = 2
+
+	got, err := search.Do(client, server.Domain(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages := server.Requests("/s"); pages != 2 {
+		t.Errorf("got %d pages requested; want 2", pages)
+	}
+	if len(got) <= len(resultsPaths) {
+		t.Errorf("got %d urls %v; want the urls of the second page too", len(got), got)
+	}
+}
+
+func TestSearcher(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+	for _, path := range resultsPaths {
+		server.Handle(path, amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	}
+
+	searcher := search.New(time.Minute)
+	searcher.Client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+	for i := 0; i < 2; i++ {
+		prods, err := searcher.Search(server.Domain(), rtxQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(prods) != len(resultsPaths) {
+			t.Fatalf("got %d products; want %d", len(prods), len(resultsPaths))
+		}
+		for _, prod := range prods {
+			if prod.Name == "" {
+				t.Errorf("product %q missing name", prod.URL)
+			}
+			if prod.Price.Amount <= 0 {
+				t.Errorf("product %q missing price", prod.URL)
 			}
+		}
+	}
 
-			for i, prod := range res {
+	for _, path := range resultsPaths {
+		if got := server.Requests(path); got != 1 {
+			t.Errorf("got %d requests for %q; want 1 since it should be cached", got, path)
+		}
+	}
+}
 
-				if prod.Name == "" {
-					t.Errorf("prod %d missing name on product", i)
-				}
+func TestSearcherErrors(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+	server.Handle(resultsPaths[0], amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	server.Handle(resultsPaths[1], amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	server.Handle(resultsPaths[2], amazontest.Page{File: amazontest.Fixture("captcha.html"), Status: 503})
 
-				if prod.Price <= 0 {
-					t.Errorf("prod %d missing price on product", i)
-				}
+	searcher := search.New(time.Minute)
+	searcher.Client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+	prods, err := searcher.Search(server.Domain(), rtxQuery)
+	if len(prods) != 2 {
+		t.Errorf("got %d products; want partial result of 2", len(prods))
+	}
+	if !errors.Is(err, fetch.ErrRateLimited) {
+		t.Errorf("got err %v; want %v", err, fetch.ErrRateLimited)
+	}
+	if !errors.Is(err, fetch.ErrNotFound) {
+		t.Errorf("got err %v; want %v", err, fetch.ErrNotFound)
+	}
+
+	var errs product.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got err %T; want %T", err, errs)
+	}
+
+	failed := []string{}
+	for _, err := range errs {
+		var urlErr *product.URLError
+		if !errors.As(err, &urlErr) {
+			t.Fatalf("got err %T; want %T", err, urlErr)
+		}
+		failed = append(failed, urlErr.URL)
+	}
+	assertURLs(t, failed, []string{
+		server.URL + resultsPaths[2],
+		server.URL + resultsPaths[3],
+	})
+}
+
+func TestSearcherCaptcha(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("captcha.html")})
+
+	searcher := search.New(0)
+	searcher.Client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+	_, err := searcher.Search(server.Domain(), rtxQuery)
+	if !errors.Is(err, search.ErrCaptcha) {
+		t.Errorf("got err %v; want %v", err, search.ErrCaptcha)
+	}
+	if !errors.Is(err, fetch.ErrCaptcha) {
+		t.Errorf("got err %v; want %v", err, fetch.ErrCaptcha)
+	}
+}
+
+func TestSearcherConcurrency(t *testing.T) {
+	const searches = 8
+
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+	for _, path := range resultsPaths {
+		server.Handle(path, amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	}
+
+	searcher := search.New(time.Minute)
+	searcher.Client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+	var wg sync.WaitGroup
+	wg.Add(searches)
+
+	for i := 0; i < searches; i++ {
+		go func() {
+			defer wg.Done()
+
+			prods, err := searcher.Search(server.Domain(), rtxQuery)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(prods) != len(resultsPaths) {
+				t.Errorf("got %d products; want %d", len(prods), len(resultsPaths))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for _, path := range resultsPaths {
+		if got := server.Requests(path); got != 1 {
+			t.Errorf("got %d requests for %q; want 1", got, path)
+		}
+	}
+
+	stats := searcher.Stats()
+	total := stats.Hits + stats.Misses + stats.Shared
+	if total != searches*uint64(len(resultsPaths)) {
+		t.Errorf("got %d hits+misses+shared; want %d; stats: %+v", total, searches*len(resultsPaths), stats)
+	}
+	if stats.Misses != uint64(len(resultsPaths)) {
+		t.Errorf("got %d misses; want %d; stats: %+v", stats.Misses, len(resultsPaths), stats)
+	}
+	if stats.Entries != len(resultsPaths) {
+		t.Errorf("got %d entries; want %d; stats: %+v", stats.Entries, len(resultsPaths), stats)
+	}
+}
+
+func TestSearcherSharedFetchCancelled(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+	for _, path := range resultsPaths {
+		server.Handle(path, amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	}
+
+	// Products are fetched only after released, so the
+	// second search waits for the fetches of the first
+	release := make(chan struct{})
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/s" {
+			select {
+			case <-release:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	searcher := search.New(time.Minute)
+	searcher.Client = &fetch.Client{
+		Scheme:     "http",
+		Limiter:    fetch.NewLimiter(0, 1),
+		Retry:      &fetch.Retry{Attempts: 1},
+		HTTPClient: &http.Client{Transport: transport},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ownerErr := make(chan error)
+	go func() {
+		_, err := searcher.SearchContext(ctx, server.Domain(), rtxQuery)
+		ownerErr <- err
+	}()
+	waitStats(t, searcher, func(stats search.CacheStats) bool {
+		return stats.Misses == uint64(len(resultsPaths))
+	})
+
+	type result struct {
+		prods []product.Product
+		err   error
+	}
+	waiter := make(chan result)
+	go func() {
+		prods, err := searcher.Search(server.Domain(), rtxQuery)
+		waiter <- result{prods, err}
+	}()
+	waitStats(t, searcher, func(stats search.CacheStats) bool {
+		return stats.Shared == uint64(len(resultsPaths))
+	})
+
+	cancel()
+	if err := <-ownerErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got owner err %v; want %v", err, context.Canceled)
+	}
+	close(release)
+
+	// The cancellation of the first search is not the second search failure
+	res := <-waiter
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if len(res.prods) != len(resultsPaths) {
+		t.Errorf("got %d products; want %d", len(res.prods), len(resultsPaths))
+	}
+}
+
+func TestSearcherCacheSize(t *testing.T) {
+	const cacheSize = 2
+
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+	for _, path := range resultsPaths {
+		server.Handle(path, amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	}
+
+	searcher := search.NewWithCacheSize(time.Minute, cacheSize)
+	searcher.Client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+	if _, err := searcher.Search(server.Domain(), rtxQuery); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := searcher.Stats()
+	if stats.Entries != cacheSize {
+		t.Errorf("got %d entries; want %d; stats: %+v", stats.Entries, cacheSize, stats)
+	}
+	if stats.Evictions != uint64(len(resultsPaths)-cacheSize) {
+		t.Errorf("got %d evictions; want %d; stats: %+v", stats.Evictions, len(resultsPaths)-cacheSize, stats)
+	}
+}
+
+func TestMemoryCacheDisabled(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		cache := search.NewMemoryCache(size)
+		if err := cache.Put("B08KWLMZV4", product.Product{Name: "RTX 3070"}, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if prod, ok := cache.Get("B08KWLMZV4"); ok {
+			t.Errorf("size %d: got cached product %+v; want nothing cached", size, prod)
+		}
+		if cache.Len() != 0 || cache.Evictions() != 0 {
+			t.Errorf("size %d: got %d entries and %d evictions; want none", size, cache.Len(), cache.Evictions())
+		}
+	}
+}
+
+func TestSearcherFileCache(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+	for _, path := range resultsPaths {
+		server.Handle(path, amazontest.Page{File: amazontest.Fixture("product_priceblock_ourprice.html")})
+	}
+
+	cachePath := filepath.Join(t.TempDir(), "products.jsonl")
+
+	// Each searcher simulates a new run of the process
+	for i := 0; i < 3; i++ {
+		cache, err := search.OpenFileCache(cachePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		searcher := search.NewWithCache(time.Hour, cache)
+		searcher.Client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+		prods, err := searcher.Search(server.Domain(), rtxQuery)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(prods) != len(resultsPaths) {
+			t.Fatalf("got %d products; want %d", len(prods), len(resultsPaths))
+		}
+		if err := cache.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		stats := searcher.Stats()
+		if i > 0 && stats.Hits != uint64(len(resultsPaths)) {
+			t.Errorf("run %d got %d hits; want %d; stats: %+v", i, stats.Hits, len(resultsPaths), stats)
+		}
+	}
+
+	for _, path := range resultsPaths {
+		if got := server.Requests(path); got != 1 {
+			t.Errorf("got %d requests for %q; want 1", got, path)
+		}
+	}
+
+	t.Run("Expired", func(t *testing.T) {
+		cache, err := search.OpenFileCache(cachePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cache.Close()
+
+		for i, path := range resultsPaths {
+			key := product.Key(server.URL + path)
+			if _, ok := cache.Get(key); !ok {
+				t.Fatalf("missing cached product %q", key)
+			}
+			deadline := time.Now().Add(-time.Minute)
+			if i%2 == 0 {
+				deadline = time.Now().Add(time.Minute)
+			}
+			if err := cache.Put(key, product.Product{URL: key}, deadline); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want := len(resultsPaths) / 2
+		if got := cache.Len(); got != len(resultsPaths) {
+			t.Fatalf("got %d entries before reopening; want %d", got, len(resultsPaths))
+		}
+
+		reopened, err := search.OpenFileCache(cachePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reopened.Close()
+
+		if got := reopened.Len(); got != want {
+			t.Fatalf("got %d entries after reopening; want %d", got, want)
+		}
+	})
+}
+
+func TestParseResultsURLs(t *testing.T) {
+	f, err := os.Open(amazontest.Fixture("search_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, next, err := search.ParseResultsPage(f, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertURLs(t, got, resultsPaths)
+
+	const wantNext = "/s?k=nvidia+rtx+3070&low-price=500&high-price=1500&page=2&qid=1606841550&ref=sr_pg_1"
+	if next != wantNext {
+		t.Errorf("got next page %q; want %q", next, wantNext)
+	}
+}
+
+func TestParseResultsURLsFailures(t *testing.T) {
+	type Test struct {
+		name        string
+		page        string
+		wantCaptcha bool
+	}
+
+	tests := []Test{
+		{
+			name:        "Captcha",
+			page:        amazontest.Fixture("captcha.html"),
+			wantCaptcha: true,
+		},
+		{
+			name:        "NoResults",
+			page:        amazontest.Fixture("search_no_results.html"),
+			wantCaptcha: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := os.Open(test.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			urls, _, err := search.ParseResultsPage(f, 1)
+			if err == nil {
+				t.Fatalf("want error, got urls: %v", urls)
+			}
+
+			gotCaptcha := errors.Is(err, search.ErrCaptcha)
+			if gotCaptcha != test.wantCaptcha {
+				t.Fatalf("got captcha %t; want %t; err: %v", gotCaptcha, test.wantCaptcha, err)
 			}
 		})
 	}
 }
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// waitStats waits until the cache stats of the searcher are ready.
+func waitStats(t *testing.T, searcher *search.Searcher, ready func(search.CacheStats) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !ready(searcher.Stats()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting stats, got %+v", searcher.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func assertURLs(t *testing.T, got []string, want []string) {
+	t.Helper()
+
+	got = append([]string{}, got...)
+	want = append([]string{}, want...)
+	sort.Strings(got)
+	sort.Strings(want)
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d urls %v; want %d urls %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got url[%d] %q; want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/search"
+)
+
+// Config is the watchlist configuration, usually loaded from a JSON file
+// with LoadConfig. Durations are strings like "10m" and prices are
+// strings like "$650" or "650.00 EUR".
+type Config struct {
+	Interval   string           `json:"interval"`
+	Jitter     string           `json:"jitter"`
+	MaxBackoff string           `json:"max_backoff"`
+	Targets    []TargetConfig   `json:"targets"`
+	Notifiers  []NotifierConfig `json:"notifiers"`
+}
+
+// TargetConfig is the configuration of a Target, with either a link
+// or a domain and a search. The domain defaults to www.amazon.com.
+type TargetConfig struct {
+	Name        string        `json:"name"`
+	Link        string        `json:"link"`
+	Domain      string        `json:"domain"`
+	Search      *SearchConfig `json:"search"`
+	MaxPrice    string        `json:"max_price"`
+	DropPercent float64       `json:"drop_percent"`
+	Restock     bool          `json:"restock"`
+}
+
+// SearchConfig is the configuration of a search.Query, Sort and
+// Condition are the values of search.Sort and search.Condition.
+type SearchConfig struct {
+	Keywords   string `json:"keywords"`
+	MinPrice   string `json:"min_price"`
+	MaxPrice   string `json:"max_price"`
+	Sort       string `json:"sort"`
+	Department string `json:"department"`
+	PrimeOnly  bool   `json:"prime_only"`
+	Condition  string `json:"condition"`
+	Brand      string `json:"brand"`
+	Seller     string `json:"seller"`
+	MaxPages   int    `json:"max_pages"`
+	MaxResults int    `json:"max_results"`
+}
+
+// NotifierConfig is the configuration of a notifier. Type is one of
+// "stdout", "webhook", "email" or "command", and only the fields
+// of that type are used.
+type NotifierConfig struct {
+	Type string `json:"type"`
+	// URL of the webhook.
+	URL string `json:"url"`
+	// Addr, From and To of the email.
+	Addr string   `json:"addr"`
+	From string   `json:"from"`
+	To   []string `json:"to"`
+	// Command and Args of the command.
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+type Error string
+
+const ErrInvalidConfig Error = "invalid config"
+
+// LoadConfig loads the config from the JSON file on the given path.
+func LoadConfig(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+
+	return ParseConfig(file)
+}
+
+// ParseConfig parses the config from JSON.
+func ParseConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("%w : %v", ErrInvalidConfig, err)
+	}
+	return cfg, nil
+}
+
+// Watcher creates a Watcher with the config, validating it.
+// The client, errors handling and other options can be
+// set on the returned Watcher.
+func (cfg Config) Watcher() (*Watcher, error) {
+	w := &Watcher{}
+
+	var err error
+	if w.Interval, err = parseDuration("interval", cfg.Interval); err != nil {
+		return nil, err
+	}
+	if w.Jitter, err = parseDuration("jitter", cfg.Jitter); err != nil {
+		return nil, err
+	}
+	if w.MaxBackoff, err = parseDuration("max_backoff", cfg.MaxBackoff); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("%w : no targets", ErrInvalidConfig)
+	}
+	for i, tc := range cfg.Targets {
+		target, err := tc.target()
+		if err != nil {
+			return nil, fmt.Errorf("%w : target %d : %v", ErrInvalidConfig, i, err)
+		}
+		w.Targets = append(w.Targets, target)
+	}
+
+	for i, nc := range cfg.Notifiers {
+		notifier, err := nc.notifier()
+		if err != nil {
+			return nil, fmt.Errorf("%w : notifier %d : %v", ErrInvalidConfig, i, err)
+		}
+		w.Notifiers = append(w.Notifiers, notifier)
+	}
+
+	return w, nil
+}
+
+func (tc TargetConfig) target() (Target, error) {
+	target := Target{
+		Name:        tc.Name,
+		Link:        tc.Link,
+		Domain:      tc.Domain,
+		DropPercent: tc.DropPercent,
+		Restock:     tc.Restock,
+	}
+
+	var err error
+	if target.MaxPrice, err = parsePrice("max_price", tc.MaxPrice); err != nil {
+		return Target{}, err
+	}
+
+	switch {
+	case tc.Link != "" && tc.Search != nil:
+		return Target{}, fmt.Errorf("target %q has both link and search", tc.Name)
+	case tc.Link != "":
+	case tc.Search != nil:
+		if target.Query, err = tc.Search.query(); err != nil {
+			return Target{}, err
+		}
+		if target.Domain == "" {
+			target.Domain = "www.amazon.com"
+		}
+		if _, err := target.Query.ValuesFor(target.Domain); err != nil {
+			return Target{}, err
+		}
+	default:
+		return Target{}, fmt.Errorf("target %q has no link or search", tc.Name)
+	}
+
+	if target.Name == "" {
+		target.Name = tc.Link
+		if tc.Search != nil {
+			target.Name = tc.Search.Keywords
+		}
+	}
+
+	if target.MaxPrice.IsZero() && target.DropPercent <= 0 && !target.Restock {
+		return Target{}, fmt.Errorf("target %q has nothing to notify, set max_price, drop_percent or restock", target.Name)
+	}
+	return target, nil
+}
+
+func (sc SearchConfig) query() (search.Query, error) {
+	q := search.Query{
+		Keywords:   sc.Keywords,
+		Sort:       search.Sort(sc.Sort),
+		Department: sc.Department,
+		PrimeOnly:  sc.PrimeOnly,
+		Condition:  search.Condition(sc.Condition),
+		Brand:      sc.Brand,
+		Seller:     sc.Seller,
+		MaxPages:   sc.MaxPages,
+		MaxResults: sc.MaxResults,
+	}
+
+	var err error
+	if q.MinPrice, err = parsePrice("min_price", sc.MinPrice); err != nil {
+		return search.Query{}, err
+	}
+	if q.MaxPrice, err = parsePrice("max_price", sc.MaxPrice); err != nil {
+		return search.Query{}, err
+	}
+	return q, nil
+}
+
+func (nc NotifierConfig) notifier() (Notifier, error) {
+	switch nc.Type {
+	case "stdout":
+		return &Writer{W: os.Stdout}, nil
+	case "webhook":
+		if nc.URL == "" {
+			return nil, fmt.Errorf("webhook has no url")
+		}
+		return &Webhook{URL: nc.URL}, nil
+	case "email":
+		if nc.From == "" || len(nc.To) == 0 {
+			return nil, fmt.Errorf("email needs from and to")
+		}
+		addr := nc.Addr
+		if addr == "" {
+			addr = "localhost:25"
+		}
+		return &Email{Addr: addr, From: nc.From, To: nc.To}, nil
+	case "command":
+		if nc.Command == "" {
+			return nil, fmt.Errorf("command notifier has no command")
+		}
+		return &Command{Name: nc.Command, Args: nc.Args}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type %q", nc.Type)
+}
+
+func parseDuration(name, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w : %s : %v", ErrInvalidConfig, name, err)
+	}
+	return d, nil
+}
+
+func parsePrice(name, s string) (money.Money, error) {
+	if s == "" {
+		return money.Money{}, nil
+	}
+	m, err := money.Parse(s)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("%s : %v", name, err)
+	}
+	return m, nil
+}
+
+func (e Error) Error() string {
+	return string(e)
+}
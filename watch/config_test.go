@@ -0,0 +1,163 @@
+package watch_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/search"
+	"github.com/katcipis/amazoner/watch"
+)
+
+func TestConfig(t *testing.T) {
+	const config = `{
+		"interval": "15m",
+		"jitter": "3m",
+		"max_backoff": "2h",
+		"targets": [
+			{
+				"name": "rtx 3070",
+				"domain": "www.amazon.de",
+				"search": {"keywords": "rtx 3070", "max_price": "800 EUR", "sort": "price-asc-rank", "max_pages": 2},
+				"max_price": "650 EUR",
+				"drop_percent": 5
+			},
+			{
+				"link": "https://www.amazon.com/dp/B08KWLMZV4",
+				"restock": true
+			}
+		],
+		"notifiers": [
+			{"type": "stdout"},
+			{"type": "webhook", "url": "http://localhost:8080/hook"},
+			{"type": "email", "from": "amazoner@localhost", "to": ["me@localhost"]},
+			{"type": "command", "command": "notify-send", "args": ["-u", "critical"]}
+		]
+	}`
+
+	cfg, err := watch.ParseConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := cfg.Watcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Interval != 15*time.Minute || w.Jitter != 3*time.Minute || w.MaxBackoff != 2*time.Hour {
+		t.Errorf("got interval %v jitter %v max backoff %v; want 15m 3m 2h", w.Interval, w.Jitter, w.MaxBackoff)
+	}
+
+	if len(w.Targets) != 2 {
+		t.Fatalf("got %d targets; want 2", len(w.Targets))
+	}
+
+	wantSearch := watch.Target{
+		Name:   "rtx 3070",
+		Domain: "www.amazon.de",
+		Query: search.Query{
+			Keywords: "rtx 3070",
+			MaxPrice: money.Money{Amount: 80000, Currency: "EUR"},
+			Sort:     search.SortPriceAsc,
+			MaxPages: 2,
+		},
+		MaxPrice:    money.Money{Amount: 65000, Currency: "EUR"},
+		DropPercent: 5,
+	}
+	if w.Targets[0] != wantSearch {
+		t.Errorf("got target %+v; want %+v", w.Targets[0], wantSearch)
+	}
+
+	wantLink := watch.Target{
+		Name:    "https://www.amazon.com/dp/B08KWLMZV4",
+		Link:    "https://www.amazon.com/dp/B08KWLMZV4",
+		Restock: true,
+	}
+	if w.Targets[1] != wantLink {
+		t.Errorf("got target %+v; want %+v", w.Targets[1], wantLink)
+	}
+
+	if len(w.Notifiers) != 4 {
+		t.Fatalf("got %d notifiers; want 4", len(w.Notifiers))
+	}
+	email, ok := w.Notifiers[2].(*watch.Email)
+	if !ok {
+		t.Fatalf("got notifier %T; want *watch.Email", w.Notifiers[2])
+	}
+	if email.Addr != "localhost:25" {
+		t.Errorf("got email addr %q; want default local SMTP server", email.Addr)
+	}
+}
+
+func TestConfigFailures(t *testing.T) {
+	type Test struct {
+		name   string
+		config string
+	}
+
+	tests := []Test{
+		{
+			name:   "InvalidJSON",
+			config: `{"targets": [`,
+		},
+		{
+			name:   "UnknownField",
+			config: `{"targets": [{"link": "https://www.amazon.com/dp/B08KWLMZV4", "restock": true}], "unknown": 1}`,
+		},
+		{
+			name:   "NoTargets",
+			config: `{"targets": []}`,
+		},
+		{
+			name:   "InvalidInterval",
+			config: `{"interval": "often", "targets": [{"link": "https://www.amazon.com/dp/B08KWLMZV4", "restock": true}]}`,
+		},
+		{
+			name:   "NoLinkOrSearch",
+			config: `{"targets": [{"name": "nothing", "restock": true}]}`,
+		},
+		{
+			name:   "LinkAndSearch",
+			config: `{"targets": [{"link": "https://www.amazon.com/dp/B08KWLMZV4", "search": {"keywords": "rtx"}, "restock": true}]}`,
+		},
+		{
+			name:   "NothingToNotify",
+			config: `{"targets": [{"link": "https://www.amazon.com/dp/B08KWLMZV4"}]}`,
+		},
+		{
+			name:   "InvalidMaxPrice",
+			config: `{"targets": [{"link": "https://www.amazon.com/dp/B08KWLMZV4", "max_price": "cheap"}]}`,
+		},
+		{
+			name:   "InvalidQuery",
+			config: `{"targets": [{"search": {"keywords": "rtx", "sort": "cheapest"}, "restock": true}]}`,
+		},
+		{
+			name:   "UnknownNotifier",
+			config: `{"targets": [{"link": "https://www.amazon.com/dp/B08KWLMZV4", "restock": true}], "notifiers": [{"type": "pigeon"}]}`,
+		},
+		{
+			name:   "WebhookWithoutURL",
+			config: `{"targets": [{"link": "https://www.amazon.com/dp/B08KWLMZV4", "restock": true}], "notifiers": [{"type": "webhook"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cfg, err := watch.ParseConfig(strings.NewReader(test.config))
+			if err == nil {
+				_, err = cfg.Watcher()
+			}
+			if err == nil {
+				t.Fatal("want error")
+			}
+			if !errors.Is(err, watch.ErrInvalidConfig) {
+				t.Errorf("got error %v; want %v", err, watch.ErrInvalidConfig)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+package watch
+
+import "time"
+
+func NextInterval(w *Watcher, failures int) time.Duration {
+	w.init()
+	return w.nextInterval(failures)
+}
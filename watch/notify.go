@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Writer notifies events writing them as lines on W, like os.Stdout.
+type Writer struct {
+	W io.Writer
+
+	mu sync.Mutex
+}
+
+// Notify writes the event.
+func (n *Writer) Notify(ctx context.Context, ev Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err := fmt.Fprintf(n.W, "%s [%s] %s: %s %s\n",
+		ev.Time.Format("2006-01-02 15:04:05"), ev.Kind, ev.Target, ev.Message, ev.Product.URL)
+	return err
+}
+
+// Webhook notifies events with a POST of the event as JSON to URL.
+type Webhook struct {
+	URL string
+	// Client is the HTTP client, if nil http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Notify posts the event.
+func (n *Webhook) Notify(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("webhook %q unexpected status %d; resp body:\n%s", n.URL, res.StatusCode, resBody)
+	}
+	return nil
+}
+
+// Email notifies events sending emails through a SMTP server,
+// usually a local one like "localhost:25".
+type Email struct {
+	Addr string
+	From string
+	To   []string
+	// Auth is the SMTP authentication, if nil there is no authentication.
+	Auth smtp.Auth
+}
+
+// Notify sends the event email.
+func (n *Email) Notify(ctx context.Context, ev Event) error {
+	msg := strings.Builder{}
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", title(ev))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(&msg, "\r\n%s\r\n\r\n%s\r\n", ev.Message, ev.Product.URL)
+
+	return smtp.SendMail(n.Addr, n.Auth, n.From, n.To, []byte(msg.String()))
+}
+
+// Command notifies events running a command with the event title and
+// message appended to Args, like notify-send for desktop notifications.
+type Command struct {
+	Name string
+	Args []string
+}
+
+// Notify runs the command.
+func (n *Command) Notify(ctx context.Context, ev Event) error {
+	args := append(append([]string{}, n.Args...), title(ev), ev.Message)
+	output, err := exec.CommandContext(ctx, n.Name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("running %q : %v; output:\n%s", n.Name, err, output)
+	}
+	return nil
+}
+
+func title(ev Event) string {
+	return fmt.Sprintf("amazoner %s: %s", ev.Kind, ev.Target)
+}
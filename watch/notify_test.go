@@ -0,0 +1,111 @@
+package watch_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/product"
+	"github.com/katcipis/amazoner/watch"
+)
+
+var event = watch.Event{
+	Kind:    watch.PriceBelow,
+	Target:  "rtx 3070",
+	Time:    time.Date(2021, 1, 2, 15, 4, 5, 0, time.UTC),
+	Message: "MSI RTX 3070: landed price $599.99 is at or below $650.00",
+	Product: product.Product{URL: "https://www.amazon.com/dp/B08KWLMZV4", Name: "MSI RTX 3070"},
+}
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	n := &watch.Writer{W: buf}
+
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "2021-01-02 15:04:05 [price-below] rtx 3070: MSI RTX 3070: landed price $599.99 is at or below $650.00 https://www.amazon.com/dp/B08KWLMZV4\n"
+	if buf.String() != want {
+		t.Errorf("got %q; want %q", buf.String(), want)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got watch.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			t.Errorf("got method %q; want POST", req.Method)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			t.Errorf("decoding event : %v", err)
+		}
+	}))
+	defer server.Close()
+
+	n := &watch.Webhook{URL: server.URL}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Kind != event.Kind || got.Message != event.Message || got.Product.URL != event.Product.URL {
+		t.Errorf("got event %+v; want %+v", got, event)
+	}
+}
+
+func TestWebhookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	}))
+	defer server.Close()
+
+	n := &watch.Webhook{URL: server.URL}
+	err := n.Notify(context.Background(), event)
+	if err == nil {
+		t.Fatal("want error on unexpected status")
+	}
+	if !strings.Contains(err.Error(), "boom") {
+		t.Errorf("got error %q; want it to have the response body", err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "notification")
+	n := &watch.Command{
+		Name: "sh",
+		Args: []string{"-c", `printf '%s|%s' "$0" "$1" > "` + out + `"`},
+	}
+
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "amazoner price-below: rtx 3070|" + event.Message
+	if string(got) != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestCommandFailure(t *testing.T) {
+	n := &watch.Command{Name: "sh", Args: []string{"-c", "echo failed; exit 1"}}
+
+	err := n.Notify(context.Background(), event)
+	if err == nil {
+		t.Fatal("want error when command fails")
+	}
+	if !strings.Contains(err.Error(), "failed") {
+		t.Errorf("got error %q; want it to have the command output", err)
+	}
+}
@@ -0,0 +1,286 @@
+// Package watch periodically checks products, notifying
+// when their prices drop or when they are back in stock.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/product"
+	"github.com/katcipis/amazoner/search"
+)
+
+// Target is something being watched, a single product
+// or all the products found by a search.
+type Target struct {
+	// Name identifies the target on the notifications.
+	Name string
+	// Link is the link of the watched product.
+	Link string
+	// Domain and Query are the search of the watched products,
+	// used when there is no Link.
+	Domain string
+	Query  search.Query
+
+	// MaxPrice notifies when the landed price of a product
+	// gets at or below it, zero disables the notification.
+	MaxPrice money.Money
+	// DropPercent notifies when the landed price of a product
+	// drops at least this percentage since the previous check,
+	// zero disables the notification.
+	DropPercent float64
+	// Restock notifies when a product is back in stock.
+	Restock bool
+}
+
+// EventKind is the kind of a notified event.
+type EventKind string
+
+const (
+	PriceBelow EventKind = "price-below"
+	PriceDrop  EventKind = "price-drop"
+	Restock    EventKind = "restock"
+)
+
+// Event is something that happened to a watched product.
+type Event struct {
+	Kind    EventKind       `json:"kind"`
+	Target  string          `json:"target"`
+	Time    time.Time       `json:"time"`
+	Message string          `json:"message"`
+	Product product.Product `json:"product"`
+}
+
+// Notifier notifies events.
+type Notifier interface {
+	Notify(ctx context.Context, ev Event) error
+}
+
+// Defaults of the Watcher.
+const (
+	DefaultInterval   = 10 * time.Minute
+	DefaultMaxBackoff = time.Hour
+)
+
+// Watcher checks the targets periodically, notifying the events
+// of their products. It is NOT safe for concurrent use.
+type Watcher struct {
+	Targets   []Target
+	Notifiers []Notifier
+
+	// Client is used to get products and search, if nil
+	// a default client is used.
+	Client *fetch.Client
+	// Interval is the interval between checks, defaults to DefaultInterval.
+	Interval time.Duration
+	// Jitter is the max random duration added to each interval, so
+	// checks don't happen on a predictable schedule.
+	Jitter time.Duration
+	// MaxBackoff is the max interval between checks when all targets
+	// fail without products, the interval doubles after each failure
+	// up to MaxBackoff. Defaults to DefaultMaxBackoff.
+	MaxBackoff time.Duration
+	// OnError is called with errors that don't stop the watcher, like
+	// failures to check a target or to notify, if nil they are ignored.
+	OnError func(error)
+
+	rand     *rand.Rand
+	searcher *search.Searcher
+	state    map[string]product.Product
+}
+
+// Run checks the targets until the context is cancelled,
+// returning the context error.
+func (w *Watcher) Run(ctx context.Context) error {
+	w.init()
+
+	failures := 0
+	for {
+		_, checked, _ := w.poll(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Partial failures, like some products of a search, don't back off
+		if len(w.Targets) > 0 && checked == 0 {
+			failures++
+		} else {
+			failures = 0
+		}
+
+		if err := fetch.Sleep(ctx, w.nextInterval(failures)); err != nil {
+			return err
+		}
+	}
+}
+
+// Check checks all targets once, notifying and returning the events.
+// If some targets fail the events of the others are returned
+// with the error, a product.Errors with an error for each failure.
+func (w *Watcher) Check(ctx context.Context) ([]Event, error) {
+	w.init()
+
+	events, _, errs := w.poll(ctx)
+	if len(errs) == 0 {
+		return events, nil
+	}
+	return events, product.Errors(errs)
+}
+
+func (w *Watcher) init() {
+	if w.state != nil {
+		return
+	}
+	w.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	w.state = map[string]product.Product{}
+	// No caching, each check must see the current prices
+	w.searcher = search.New(0)
+	w.searcher.Client = w.Client
+	// Products out of stock are watched to notify when restocked
+	w.searcher.KeepUnpriced = true
+}
+
+// poll checks all targets once, returning the events and how many
+// targets were checked, the ones with products despite any errors.
+func (w *Watcher) poll(ctx context.Context) ([]Event, int, []error) {
+	var (
+		events  []Event
+		checked int
+		errs    []error
+	)
+
+	for _, target := range w.Targets {
+		products, err := w.products(ctx, target)
+		if err != nil {
+			err = fmt.Errorf("checking target %q : %w", target.Name, err)
+			errs = append(errs, err)
+			w.onError(err)
+			if len(products) == 0 {
+				continue
+			}
+		}
+		checked++
+
+		for _, prod := range products {
+			for _, ev := range w.detect(target, prod) {
+				events = append(events, ev)
+				w.notify(ctx, ev)
+			}
+		}
+	}
+
+	return events, checked, errs
+}
+
+func (w *Watcher) products(ctx context.Context, target Target) ([]product.Product, error) {
+	if target.Link != "" {
+		prod, err := product.GetContext(ctx, w.Client, target.Link)
+		if err != nil && !product.IsPriceMissing(prod, err) {
+			return nil, err
+		}
+		return []product.Product{prod}, nil
+	}
+	return w.searcher.SearchContext(ctx, target.Domain, target.Query)
+}
+
+// detect detects the events of the product since its previous check.
+func (w *Watcher) detect(target Target, prod product.Product) []Event {
+	key := prod.CanonicalURL
+	if key == "" {
+		key = prod.URL
+	}
+
+	prev, seen := w.state[key]
+	w.state[key] = prod
+
+	events := []Event{}
+	event := func(kind EventKind, format string, args ...interface{}) {
+		events = append(events, Event{
+			Kind:    kind,
+			Target:  target.Name,
+			Time:    time.Now(),
+			Message: fmt.Sprintf("%s: ", prod.Name) + fmt.Sprintf(format, args...),
+			Product: prod,
+		})
+	}
+
+	if target.Restock && seen && !prev.InStock() && prod.InStock() {
+		event(Restock, "back in stock, %q", prod.Availability)
+	}
+
+	// Products out of stock usually have no price
+	if prod.Price.IsZero() {
+		return events
+	}
+
+	landed, err := prod.LandedPrice()
+	if err != nil {
+		w.onError(fmt.Errorf("target %q product %q : %w", target.Name, prod.URL, err))
+		return events
+	}
+	prevLanded, _ := prev.LandedPrice()
+
+	if !target.MaxPrice.IsZero() && atOrBelow(landed, target.MaxPrice) {
+		// Only when crossing the limit, so it is not notified on every check
+		if !seen || prev.Price.IsZero() || !atOrBelow(prevLanded, target.MaxPrice) {
+			event(PriceBelow, "landed price %v is at or below %v", landed, target.MaxPrice)
+		}
+	}
+
+	if target.DropPercent > 0 && seen && prevLanded.Amount > 0 {
+		drop := float64(prevLanded.Amount-landed.Amount) / float64(prevLanded.Amount) * 100
+		if drop >= target.DropPercent {
+			event(PriceDrop, "landed price dropped %.1f%% from %v to %v", drop, prevLanded, landed)
+		}
+	}
+
+	return events
+}
+
+func (w *Watcher) notify(ctx context.Context, ev Event) {
+	for _, n := range w.Notifiers {
+		if err := n.Notify(ctx, ev); err != nil {
+			w.onError(fmt.Errorf("notifying %s of target %q : %w", ev.Kind, ev.Target, err))
+		}
+	}
+}
+
+func (w *Watcher) onError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
+
+// nextInterval returns the interval until the next check,
+// given how many checks failed in a row.
+func (w *Watcher) nextInterval(failures int) time.Duration {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	maxBackoff := w.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	for i := 0; i < failures && interval < maxBackoff; i++ {
+		interval *= 2
+	}
+	if failures > 0 && interval > maxBackoff {
+		interval = maxBackoff
+	}
+
+	if w.Jitter > 0 {
+		interval += time.Duration(w.rand.Int63n(int64(w.Jitter)))
+	}
+	return interval
+}
+
+func atOrBelow(price, limit money.Money) bool {
+	cmp, err := price.Cmp(limit)
+	return err == nil && cmp <= 0
+}
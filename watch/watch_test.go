@@ -0,0 +1,317 @@
+package watch_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/katcipis/amazoner/amazontest"
+	"github.com/katcipis/amazoner/fetch"
+	"github.com/katcipis/amazoner/money"
+	"github.com/katcipis/amazoner/search"
+	"github.com/katcipis/amazoner/watch"
+)
+
+// client has no rate limit, so tests run fast
+var client = &fetch.Client{Scheme: "http", Limiter: fetch.NewLimiter(0, 1)}
+
+func TestWatcherCheck(t *testing.T) {
+	type Test struct {
+		page       string
+		wantEvents []watch.EventKind
+	}
+
+	tests := []Test{
+		{
+			// Out of stock without price, first check
+			page:       amazontest.Fixture("product_out_of_stock.html"),
+			wantEvents: []watch.EventKind{},
+		},
+		{
+			// $540.00 from other sellers
+			page:       amazontest.Fixture("product_unavailable.html"),
+			wantEvents: []watch.EventKind{watch.PriceBelow},
+		},
+		{
+			// $939.99 and in stock
+			page:       amazontest.Fixture("product_price_inside_buybox.html"),
+			wantEvents: []watch.EventKind{watch.Restock},
+		},
+		{
+			// $939.99 + $67.73 shipping and import fees
+			page:       amazontest.Fixture("product_import_fees.html"),
+			wantEvents: []watch.EventKind{},
+		},
+		{
+			// $939.99 again, 6.7% cheaper
+			page:       amazontest.Fixture("product_price_inside_buybox.html"),
+			wantEvents: []watch.EventKind{watch.PriceBelow, watch.PriceDrop},
+		},
+		{
+			page:       amazontest.Fixture("product_price_inside_buybox.html"),
+			wantEvents: []watch.EventKind{},
+		},
+	}
+
+	const path = "/MSI-RTX-2070-Super-Architecture/dp/B0856BVRFL"
+
+	server := amazontest.NewServer(t)
+	notifier := &recorder{}
+	w := &watch.Watcher{
+		Client: client,
+		Targets: []watch.Target{
+			{
+				Name:        "rtx 2070",
+				Link:        server.URL + path,
+				MaxPrice:    money.Money{Amount: 95000, Currency: "USD"},
+				DropPercent: 5,
+				Restock:     true,
+			},
+		},
+		Notifiers: []watch.Notifier{notifier},
+	}
+
+	for i, test := range tests {
+		server.Handle(path, amazontest.Page{File: test.page})
+
+		events, err := w.Check(context.Background())
+		if err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+
+		kinds := []watch.EventKind{}
+		for _, ev := range events {
+			kinds = append(kinds, ev.Kind)
+			if ev.Target != "rtx 2070" {
+				t.Errorf("check %d: got target %q; want %q", i, ev.Target, "rtx 2070")
+			}
+		}
+		if !reflect.DeepEqual(kinds, test.wantEvents) {
+			t.Errorf("check %d: got events %v; want %v", i, kinds, test.wantEvents)
+		}
+
+		notified := notifier.take()
+		if !reflect.DeepEqual(notified, events) {
+			t.Errorf("check %d: got notified %v; want %v", i, notified, events)
+		}
+	}
+}
+
+func TestWatcherCheckRestockWithoutPrice(t *testing.T) {
+	const path = "/dp/B0856BVRFL"
+
+	server := amazontest.NewServer(t)
+	w := &watch.Watcher{
+		Client: client,
+		Targets: []watch.Target{
+			{
+				Name:     "rtx 2070",
+				Link:     server.URL + path,
+				MaxPrice: money.Money{Amount: 95000, Currency: "USD"},
+				Restock:  true,
+			},
+		},
+	}
+
+	pages := []string{"product_out_of_stock.html", "product_price_inside_buybox.html"}
+	wantEvents := [][]watch.EventKind{{}, {watch.Restock, watch.PriceBelow}}
+
+	for i, page := range pages {
+		server.Handle(path, amazontest.Page{File: amazontest.Fixture(page)})
+
+		events, err := w.Check(context.Background())
+		if err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+
+		kinds := []watch.EventKind{}
+		for _, ev := range events {
+			kinds = append(kinds, ev.Kind)
+		}
+		if !reflect.DeepEqual(kinds, wantEvents[i]) {
+			t.Errorf("check %d: got events %v; want %v", i, kinds, wantEvents[i])
+		}
+	}
+}
+
+func TestWatcherCheckSearchRestock(t *testing.T) {
+	// The products on the search_results.html fixture
+	paths := []string{
+		"/MSI-GeForce-256-Bit-Architecture-Graphics/dp/B08KWLMZV4",
+		"/MSI-GeForce-RTX-3070-Architecture/dp/B08KWN2LZG",
+		"/PNY-GeForce-Gaming-Epic-X-Graphics/dp/B08HBJB7YD",
+		"/EVGA-08G-P5-3767-KR-GeForce-Technology-Backplate/dp/B08L8L9TCZ",
+	}
+
+	server := amazontest.NewServer(t)
+	server.Handle("/s", amazontest.Page{File: amazontest.Fixture("search_results.html")})
+
+	w := &watch.Watcher{
+		Client: client,
+		Targets: []watch.Target{
+			{
+				Name:    "rtx 3070",
+				Domain:  server.Domain(),
+				Query:   search.Query{Keywords: "nvidia rtx 3070"},
+				Restock: true,
+			},
+		},
+	}
+
+	pages := []string{"product_out_of_stock.html", "product_price_inside_buybox.html"}
+	wantRestocks := []int{0, len(paths)}
+
+	for i, page := range pages {
+		for _, path := range paths {
+			server.Handle(path, amazontest.Page{File: amazontest.Fixture(page)})
+		}
+
+		events, err := w.Check(context.Background())
+		if err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+
+		restocks := 0
+		for _, ev := range events {
+			if ev.Kind == watch.Restock {
+				restocks++
+			}
+		}
+		if restocks != wantRestocks[i] {
+			t.Errorf("check %d: got %d restocks on events %v; want %d", i, restocks, events, wantRestocks[i])
+		}
+	}
+}
+
+func TestWatcherCheckFailures(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/dp/B0856BVRFL", amazontest.Page{File: amazontest.Fixture("product_price_inside_buybox.html")})
+
+	var errs []error
+	w := &watch.Watcher{
+		Client: client,
+		Targets: []watch.Target{
+			{Name: "missing", Link: server.URL + "/dp/B000000000", Restock: true},
+			{Name: "found", Link: server.URL + "/dp/B0856BVRFL", MaxPrice: money.Money{Amount: 100000}},
+		},
+		OnError: func(err error) { errs = append(errs, err) },
+	}
+
+	events, err := w.Check(context.Background())
+	if err == nil {
+		t.Fatal("want error checking missing product")
+	}
+	if len(errs) != 1 {
+		t.Errorf("got errors %v; want only the missing product error", errs)
+	}
+	if len(events) != 1 || events[0].Kind != watch.PriceBelow || events[0].Target != "found" {
+		t.Errorf("got events %v; want price below event of found target", events)
+	}
+}
+
+func TestWatcherRunCancelled(t *testing.T) {
+	server := amazontest.NewServer(t)
+	server.Handle("/dp/B0856BVRFL", amazontest.Page{File: amazontest.Fixture("product_price_inside_buybox.html")})
+
+	w := &watch.Watcher{
+		Client:   client,
+		Interval: time.Millisecond,
+		Targets: []watch.Target{
+			{Name: "found", Link: server.URL + "/dp/B0856BVRFL", Restock: true},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := w.Run(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("got error %v; want %v", err, context.DeadlineExceeded)
+	}
+	if server.Requests("/dp/B0856BVRFL") < 2 {
+		t.Errorf("got %d requests; want the product checked periodically", server.Requests("/dp/B0856BVRFL"))
+	}
+}
+
+func TestNextInterval(t *testing.T) {
+	type Test struct {
+		name     string
+		watcher  watch.Watcher
+		failures int
+		want     time.Duration
+	}
+
+	tests := []Test{
+		{
+			name: "Defaults",
+			want: watch.DefaultInterval,
+		},
+		{
+			name:    "NoFailures",
+			watcher: watch.Watcher{Interval: time.Minute},
+			want:    time.Minute,
+		},
+		{
+			name:     "Backoff",
+			watcher:  watch.Watcher{Interval: time.Minute},
+			failures: 3,
+			want:     8 * time.Minute,
+		},
+		{
+			name:     "MaxBackoff",
+			watcher:  watch.Watcher{Interval: time.Minute, MaxBackoff: 5 * time.Minute},
+			failures: 3,
+			want:     5 * time.Minute,
+		},
+		{
+			name:     "DefaultMaxBackoff",
+			watcher:  watch.Watcher{Interval: time.Minute},
+			failures: 100,
+			want:     watch.DefaultMaxBackoff,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			got := watch.NextInterval(&test.watcher, test.failures)
+			if got != test.want {
+				t.Errorf("got interval %v; want %v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("Jitter", func(t *testing.T) {
+		w := &watch.Watcher{Interval: time.Minute, Jitter: time.Second}
+		for i := 0; i < 100; i++ {
+			got := watch.NextInterval(w, 0)
+			if got < time.Minute || got >= time.Minute+time.Second {
+				t.Fatalf("got interval %v; want between %v and %v", got, time.Minute, time.Minute+time.Second)
+			}
+		}
+	})
+}
+
+// recorder is a notifier that records the notified events.
+type recorder struct {
+	mu     sync.Mutex
+	events []watch.Event
+}
+
+func (r *recorder) Notify(ctx context.Context, ev watch.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, ev)
+	return nil
+}
+
+func (r *recorder) take() []watch.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := r.events
+	r.events = nil
+	return events
+}